@@ -0,0 +1,113 @@
+package git
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+)
+
+// Archive writes the tree named by treeish, which may be a tree, a commit or a
+// ref to either, to w as a tar archive.
+func (r *Repository) Archive(treeish string, w io.Writer) error {
+	treeHash, err := r.ResolveTree(treeish)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+
+	err = r.archiveTree(tw, treeHash, "")
+	if err != nil {
+		return err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return fmt.Errorf("failed to finish the archive: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) archiveTree(tw *tar.Writer, treeHash string, prefix string) error {
+	entries, err := r.readTreeEntries(treeHash)
+	if err != nil {
+		return fmt.Errorf("failed to read tree %s: %w", treeHash, err)
+	}
+
+	for _, entry := range entries {
+		name := path.Join(prefix, entry.Name)
+
+		switch entry.Mode {
+		case "40000", "160000":
+			err = tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     name + "/",
+				Mode:     0755,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write directory %s: %w", name, err)
+			}
+
+			/*
+				Gitlinks point into a submodule, not into this object store, so they are archived as empty directories.
+			*/
+			if entry.Mode == "160000" {
+				continue
+			}
+
+			err = r.archiveTree(tw, entry.Hash, name)
+			if err != nil {
+				return err
+			}
+
+		case "120000":
+			_, target, err := r.readObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", name, err)
+			}
+
+			err = tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeSymlink,
+				Name:     name,
+				Linkname: string(target),
+				Mode:     0777,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write symlink %s: %w", name, err)
+			}
+
+		case "100644", "100755":
+			_, contents, err := r.readObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to read blob %s: %w", name, err)
+			}
+
+			mode := int64(0644)
+			if entry.Mode == "100755" {
+				mode = 0755
+			}
+
+			err = tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     name,
+				Mode:     mode,
+				Size:     int64(len(contents)),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write header for %s: %w", name, err)
+			}
+
+			_, err = tw.Write(contents)
+			if err != nil {
+				return fmt.Errorf("failed to write contents of %s: %w", name, err)
+			}
+
+		default:
+			return fmt.Errorf("unsupported mode %s for entry %s", entry.Mode, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package git_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestArchive(t *testing.T) {
+	t.Run("Writes the tree contents with modes and directories", func(t *testing.T) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q")
+
+		err := os.MkdirAll(path.Join(root, "dir"), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		files := map[string]struct {
+			contents string
+			mode     os.FileMode
+		}{
+			"a.txt":     {"a\n", 0644},
+			"run.sh":    {"#!/bin/sh\n", 0755},
+			"dir/b.txt": {"b\n", 0644},
+		}
+		for name, file := range files {
+			err = os.WriteFile(path.Join(root, name), []byte(file.contents), file.mode)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+		}
+
+		runGit(t, root, "add", ".")
+		treeHash := runGit(t, root, "write-tree")
+
+		repository := git.NewRepository(root)
+		var buf bytes.Buffer
+		err = repository.Archive(treeHash, &buf)
+		if err != nil {
+			t.Fatalf("error archiving tree: %v", err)
+		}
+
+		type entry struct {
+			contents string
+			mode     int64
+		}
+		got := map[string]entry{}
+		tr := tar.NewReader(&buf)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("error reading archive: %v", err)
+			}
+
+			contents, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatalf("error reading entry: %v", err)
+			}
+			got[header.Name] = entry{string(contents), header.Mode}
+		}
+
+		expected := map[string]entry{
+			"a.txt":     {"a\n", 0644},
+			"run.sh":    {"#!/bin/sh\n", 0755},
+			"dir/":      {"", 0755},
+			"dir/b.txt": {"b\n", 0644},
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d entries, got %d: %v", len(expected), len(got), got)
+		}
+		for name, e := range expected {
+			if got[name] != e {
+				t.Fatalf("expected entry %s to be %v, got %v", name, e, got[name])
+			}
+		}
+	})
+
+	t.Run("Archives the tree of a commit or a ref", func(t *testing.T) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q", "-b", "main")
+
+		err := os.WriteFile(path.Join(root, "a.txt"), []byte("a\n"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+		runGit(t, root, "add", ".")
+		runGit(t, root, "commit", "-q", "-m", "initial")
+		commit := runGit(t, root, "rev-parse", "HEAD")
+
+		repository := git.NewRepository(root)
+		for _, treeish := range []string{commit, "main", "HEAD"} {
+			var buf bytes.Buffer
+			err = repository.Archive(treeish, &buf)
+			if err != nil {
+				t.Fatalf("error archiving %s: %v", treeish, err)
+			}
+
+			header, err := tar.NewReader(&buf).Next()
+			if err != nil {
+				t.Fatalf("error reading the archive of %s: %v", treeish, err)
+			}
+
+			if header.Name != "a.txt" {
+				t.Fatalf("expected a.txt in the archive of %s, got %s", treeish, header.Name)
+			}
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"fmt"
+	"io/fs"
+	"runtime"
+	"sync"
+)
+
+func (r *Repository) WriteBlobs(fsys fs.FS, filenames []string) (map[string]string, error) {
+	workers := runtime.NumCPU()
+	if workers > len(filenames) {
+		workers = len(filenames)
+	}
+
+	var (
+		mu      sync.Mutex
+		hashes  = make(map[string]string, len(filenames))
+		written = map[string]bool{}
+		errs    []error
+		wg      sync.WaitGroup
+	)
+
+	jobs := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for filename := range jobs {
+				hash, object, err := r.hashFile(fsys, filename, "blob")
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("failed to hash %s: %w", filename, err))
+					mu.Unlock()
+					continue
+				}
+
+				/*
+					Files with identical contents share an object, so only the first worker to claim a hash writes it.
+				*/
+				mu.Lock()
+				hashes[filename] = hash
+				claimed := !written[hash]
+				written[hash] = true
+				mu.Unlock()
+
+				if !claimed || r.hasObject(hash) {
+					continue
+				}
+
+				err = r.storeObject(hash, object)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("failed to write %s: %w", filename, err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, filename := range filenames {
+		jobs <- filename
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	return hashes, nil
+}
+
+func (r *Repository) hasObject(hash string) bool {
+	return r.storage.HasObject(hash)
+}
@@ -0,0 +1,95 @@
+package git_test
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestWriteBlobs(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	var filenames []string
+	for i := 0; i < 32; i++ {
+		filename := fmt.Sprintf("file-%d.txt", i)
+		filenames = append(filenames, filename)
+
+		/*
+			Only eight distinct contents, so several files share an object.
+		*/
+		err := os.WriteFile(path.Join(root, filename), []byte(fmt.Sprintf("contents %d\n", i%8)), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	hashes, err := repository.WriteBlobs(os.DirFS(root), filenames)
+	if err != nil {
+		t.Fatalf("error writing blobs: %v", err)
+	}
+
+	if len(hashes) != len(filenames) {
+		t.Fatalf("expected %d hashes, got %d", len(filenames), len(hashes))
+	}
+
+	for _, filename := range filenames {
+		expected := runGit(t, root, "hash-object", filename)
+		if hashes[filename] != expected {
+			t.Fatalf("expected %s to hash to %s, got %s", filename, expected, hashes[filename])
+		}
+
+		contents, err := repository.CatFile(expected)
+		if err != nil {
+			t.Fatalf("error reading blob for %s: %v", filename, err)
+		}
+
+		onDisk, err := os.ReadFile(path.Join(root, filename))
+		if err != nil {
+			t.Fatalf("error reading file: %v", err)
+		}
+
+		if contents != string(onDisk) {
+			t.Fatalf("expected blob of %s to be %q, got %q", filename, onDisk, contents)
+		}
+	}
+
+	t.Run("Skips objects that already exist", func(t *testing.T) {
+		objectPath := path.Join(root, ".git", "objects", hashes[filenames[0]][:2], hashes[filenames[0]][2:])
+		err := os.Chmod(objectPath, 0444)
+		if err != nil {
+			t.Fatalf("error changing mode: %v", err)
+		}
+		before, err := os.Stat(objectPath)
+		if err != nil {
+			t.Fatalf("error stating object: %v", err)
+		}
+
+		again, err := repository.WriteBlobs(os.DirFS(root), filenames[:4])
+		if err != nil {
+			t.Fatalf("error writing blobs: %v", err)
+		}
+
+		for filename, hash := range again {
+			if hashes[filename] != hash {
+				t.Fatalf("expected %s to hash to %s, got %s", filename, hashes[filename], hash)
+			}
+		}
+
+		after, err := os.Stat(objectPath)
+		if err != nil {
+			t.Fatalf("error stating object: %v", err)
+		}
+
+		if !after.ModTime().Equal(before.ModTime()) || after.Mode() != before.Mode() {
+			t.Fatalf("expected %s not to be rewritten", objectPath)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ErrBranchNotFound    = Error("branch not found")
+	ErrBranchExists      = Error("branch already exists")
+	ErrInvalidBranchName = Error("invalid branch name")
+	ErrCurrentBranch     = Error("cannot delete the current branch")
+	ErrBranchNotMerged   = Error("branch is not fully merged")
+)
+
+const branchPrefix = "refs/heads/"
+
+// errStopWalk ends a commit walk early without reporting an error.
+const errStopWalk = Error("stop walking")
+
+// Branches returns the branches and the commits at their tips, sorted by
+// name, with the refs/heads/ prefix removed.
+func (r *Repository) Branches() ([]Ref, error) {
+	refs, err := r.Refs()
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []Ref
+	for _, ref := range refs {
+		if strings.HasPrefix(ref.Name, branchPrefix) {
+			branches = append(branches, Ref{Name: strings.TrimPrefix(ref.Name, branchPrefix), Hash: ref.Hash})
+		}
+	}
+
+	return branches, nil
+}
+
+// BranchesContaining returns the branches whose tip is commit or has it as an
+// ancestor, sorted by name.
+func (r *Repository) BranchesContaining(commit string) ([]string, error) {
+	hash, err := r.RevParse(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", commit, err)
+	}
+
+	branches, err := r.Branches()
+	if err != nil {
+		return nil, err
+	}
+
+	var containing []string
+	for _, branch := range branches {
+		reachable, err := r.isAncestor(hash, branch.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		if reachable {
+			containing = append(containing, branch.Name)
+		}
+	}
+
+	return containing, nil
+}
+
+// isAncestor reports whether ancestor can be reached from tip by following
+// parents. A commit is its own ancestor.
+func (r *Repository) isAncestor(ancestor, tip string) (bool, error) {
+	found := false
+	err := r.walkCommits([]string{tip}, func(commit Commit) error {
+		if commit.Hash == ancestor {
+			found = true
+			return errStopWalk
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// RenameBranch moves a branch and its reflog to a new name, pointing HEAD at
+// the new name if it was on the old one. An existing branch with the new name
+// is only replaced when force is set.
+func (r *Repository) RenameBranch(oldName, newName string, force bool) error {
+	for _, name := range []string{oldName, newName} {
+		err := ValidateBranchName(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	oldRef, newRef := branchPrefix+oldName, branchPrefix+newName
+	value, err := r.storage.ReadRef(oldRef)
+	if err != nil {
+		if errors.Is(err, ErrRefNotFound) {
+			return fmt.Errorf("%w: %s", ErrBranchNotFound, oldName)
+		}
+
+		return err
+	}
+	if oldName == newName {
+		return nil
+	}
+
+	_, err = r.storage.ReadRef(newRef)
+	if err == nil && !force {
+		return fmt.Errorf("%w: %s", ErrBranchExists, newName)
+	}
+	if err != nil && !errors.Is(err, ErrRefNotFound) {
+		return err
+	}
+
+	reflog, err := r.storage.ReadReflog(oldRef)
+	if err != nil {
+		return err
+	}
+
+	committer, err := r.defaultSignature("committer")
+	if err != nil {
+		return err
+	}
+
+	err = r.storage.DeleteRef(oldRef)
+	if err != nil {
+		return err
+	}
+
+	err = r.storage.WriteRef(newRef, value)
+	if err != nil {
+		return err
+	}
+
+	err = r.storage.WriteReflog(oldRef, "")
+	if err != nil {
+		return err
+	}
+
+	entry := reflogEntry{old: value, new: value, committer: committer, message: fmt.Sprintf("Branch: renamed %s to %s", oldRef, newRef)}
+	err = r.storage.WriteReflog(newRef, reflog+entry.String())
+	if err != nil {
+		return err
+	}
+
+	head, err := r.storage.ReadRef("HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	if head == "ref: "+oldRef {
+		return r.storage.WriteRef("HEAD", "ref: "+newRef)
+	}
+
+	return nil
+}
+
+// DeleteBranch removes a branch and its reflog. The branch HEAD is on is never
+// deleted, and a branch whose tip HEAD cannot reach is only deleted when force
+// is set.
+func (r *Repository) DeleteBranch(name string, force bool) error {
+	err := ValidateBranchName(name)
+	if err != nil {
+		return err
+	}
+
+	ref := branchPrefix + name
+	hash, err := r.resolveRef(ref)
+	if err != nil {
+		if errors.Is(err, ErrRefNotFound) {
+			return fmt.Errorf("%w: %s", ErrBranchNotFound, name)
+		}
+
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if head.Symbolic && head.Ref == ref {
+		return fmt.Errorf("%w: %s", ErrCurrentBranch, name)
+	}
+
+	if !force {
+		merged := false
+		if head.Hash != "" {
+			merged, err = r.isAncestor(hash, head.Hash)
+			if err != nil {
+				return err
+			}
+		}
+		if !merged {
+			return fmt.Errorf("%w: %s", ErrBranchNotMerged, name)
+		}
+	}
+
+	err = r.storage.DeleteRef(ref)
+	if err != nil {
+		return err
+	}
+
+	err = r.storage.WriteReflog(ref, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateBranchName rejects names git would refuse for a branch, such as ones
+// with path components starting with a dot or containing "..".
+func ValidateBranchName(name string) error {
+	if name == "HEAD" || !validRefName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidBranchName, name)
+	}
+
+	return nil
+}
+
+// validRefName follows git check-ref-format: no empty, dot-prefixed or ".."
+// components, no leading or trailing slash, and none of the characters git
+// reserves for revision syntax.
+func validRefName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") ||
+		strings.HasSuffix(name, ".lock") || strings.Contains(name, "..") || strings.Contains(name, "//") ||
+		strings.Contains(name, "@{") || strings.ContainsAny(name, " ~^:?*[\\\x7f") {
+		return false
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+
+	for _, c := range name {
+		if c < 0x20 {
+			return false
+		}
+	}
+
+	return true
+}
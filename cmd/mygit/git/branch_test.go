@@ -0,0 +1,218 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestBranchesContaining(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "shared")
+	shared := runGit(t, root, "rev-parse", "HEAD")
+
+	runGit(t, root, "checkout", "-q", "-b", "feature")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "feature only")
+	feature := runGit(t, root, "rev-parse", "HEAD")
+
+	runGit(t, root, "checkout", "-q", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "main only")
+
+	repository := git.NewRepository(root)
+
+	tests := []struct {
+		name     string
+		commit   string
+		expected []string
+	}{
+		{name: "Finds a commit shared by both branches", commit: shared, expected: []string{"feature", "main"}},
+		{name: "Finds a commit on one branch only", commit: feature, expected: []string{"feature"}},
+		{name: "Finds a branch tip by name", commit: "main", expected: []string{"main"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branches, err := repository.BranchesContaining(tt.commit)
+			if err != nil {
+				t.Fatalf("error finding branches: %v", err)
+			}
+
+			if !reflect.DeepEqual(branches, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, branches)
+			}
+		})
+	}
+}
+
+func TestRenameBranch(t *testing.T) {
+	newRepository := func(t *testing.T) (string, git.Repository) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q", "-b", "main")
+		runGit(t, root, "config", "user.name", "Test")
+		runGit(t, root, "config", "user.email", "test@example.com")
+		runGit(t, root, "commit", "-q", "--allow-empty", "-m", "initial")
+		runGit(t, root, "branch", "feature")
+		return root, git.NewRepository(root)
+	}
+
+	t.Run("Renames the current branch", func(t *testing.T) {
+		root, repository := newRepository(t)
+		commit := runGit(t, root, "rev-parse", "main")
+
+		err := repository.RenameBranch("main", "trunk", false)
+		if err != nil {
+			t.Fatalf("error renaming branch: %v", err)
+		}
+
+		head := runGit(t, root, "symbolic-ref", "HEAD")
+		if head != "refs/heads/trunk" {
+			t.Fatalf("expected HEAD to follow the rename, got %s", head)
+		}
+
+		if hash := runGit(t, root, "rev-parse", "trunk"); hash != commit {
+			t.Fatalf("expected trunk at %s, got %s", commit, hash)
+		}
+
+		branches := runGit(t, root, "branch", "--format=%(refname:short)")
+		if branches != "feature\ntrunk" {
+			t.Fatalf("expected feature and trunk, got %q", branches)
+		}
+
+		message := runGit(t, root, "log", "-g", "-1", "--format=%gs", "trunk")
+		if message != "Branch: renamed refs/heads/main to refs/heads/trunk" {
+			t.Fatalf("expected the reflog to move with the branch, got %q", message)
+		}
+	})
+
+	t.Run("Renames another branch", func(t *testing.T) {
+		root, repository := newRepository(t)
+
+		err := repository.RenameBranch("feature", "topic/feature", false)
+		if err != nil {
+			t.Fatalf("error renaming branch: %v", err)
+		}
+
+		head := runGit(t, root, "symbolic-ref", "HEAD")
+		if head != "refs/heads/main" {
+			t.Fatalf("expected HEAD to stay on main, got %s", head)
+		}
+
+		branches := runGit(t, root, "branch", "--format=%(refname:short)")
+		if branches != "main\ntopic/feature" {
+			t.Fatalf("expected main and topic/feature, got %q", branches)
+		}
+	})
+
+	t.Run("Refuses to replace an existing branch unless forced", func(t *testing.T) {
+		root, repository := newRepository(t)
+
+		err := repository.RenameBranch("feature", "main", false)
+		if !errors.Is(err, git.ErrBranchExists) {
+			t.Fatalf("expected error %v, got %v", git.ErrBranchExists, err)
+		}
+
+		err = repository.RenameBranch("feature", "main", true)
+		if err != nil {
+			t.Fatalf("error force-renaming branch: %v", err)
+		}
+
+		branches := runGit(t, root, "branch", "--format=%(refname:short)")
+		if branches != "main" {
+			t.Fatalf("expected only main, got %q", branches)
+		}
+	})
+
+	t.Run("Fails for a missing branch or an invalid name", func(t *testing.T) {
+		_, repository := newRepository(t)
+
+		err := repository.RenameBranch("missing", "other", false)
+		if !errors.Is(err, git.ErrBranchNotFound) {
+			t.Fatalf("expected error %v, got %v", git.ErrBranchNotFound, err)
+		}
+
+		err = repository.RenameBranch("feature", "bad..name", false)
+		if !errors.Is(err, git.ErrInvalidBranchName) {
+			t.Fatalf("expected error %v, got %v", git.ErrInvalidBranchName, err)
+		}
+
+		err = repository.RenameBranch("x/../main", "other", false)
+		if !errors.Is(err, git.ErrInvalidBranchName) {
+			t.Fatalf("expected error %v, got %v", git.ErrInvalidBranchName, err)
+		}
+	})
+}
+
+func TestDeleteBranch(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "initial")
+	runGit(t, root, "branch", "merged")
+	runGit(t, root, "checkout", "-q", "-b", "unmerged")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "unmerged work")
+	runGit(t, root, "checkout", "-q", "main")
+	repository := git.NewRepository(root)
+
+	t.Run("Deletes a merged branch", func(t *testing.T) {
+		err := repository.DeleteBranch("merged", false)
+		if err != nil {
+			t.Fatalf("error deleting branch: %v", err)
+		}
+
+		branches := runGit(t, root, "branch", "--format=%(refname:short)")
+		if branches != "main\nunmerged" {
+			t.Fatalf("expected main and unmerged, got %q", branches)
+		}
+
+		_, err = os.Stat(path.Join(root, ".git", "logs", "refs", "heads", "merged"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected the reflog to be removed, got %v", err)
+		}
+	})
+
+	t.Run("Refuses the current branch", func(t *testing.T) {
+		err := repository.DeleteBranch("main", true)
+		if !errors.Is(err, git.ErrCurrentBranch) {
+			t.Fatalf("expected error %v, got %v", git.ErrCurrentBranch, err)
+		}
+	})
+
+	t.Run("Refuses an unmerged branch unless forced", func(t *testing.T) {
+		err := repository.DeleteBranch("unmerged", false)
+		if !errors.Is(err, git.ErrBranchNotMerged) {
+			t.Fatalf("expected error %v, got %v", git.ErrBranchNotMerged, err)
+		}
+
+		err = repository.DeleteBranch("unmerged", true)
+		if err != nil {
+			t.Fatalf("error force-deleting branch: %v", err)
+		}
+
+		branches := runGit(t, root, "branch", "--format=%(refname:short)")
+		if branches != "main" {
+			t.Fatalf("expected only main, got %q", branches)
+		}
+	})
+
+	t.Run("Fails for a missing branch", func(t *testing.T) {
+		err := repository.DeleteBranch("missing", true)
+		if !errors.Is(err, git.ErrBranchNotFound) {
+			t.Fatalf("expected error %v, got %v", git.ErrBranchNotFound, err)
+		}
+	})
+
+	t.Run("Refuses a name that resolves to another branch", func(t *testing.T) {
+		err := repository.DeleteBranch("x/../main", true)
+		if !errors.Is(err, git.ErrInvalidBranchName) {
+			t.Fatalf("expected error %v, got %v", git.ErrInvalidBranchName, err)
+		}
+
+		if head := runGit(t, root, "rev-parse", "--verify", "-q", "refs/heads/main"); head == "" {
+			t.Fatalf("expected main to survive")
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Commit struct {
+	Hash      string
+	Tree      string
+	Parents   []string
+	Author    Signature
+	Committer Signature
+	/*
+		Encoding names the charset of the message when it is not UTF-8.
+	*/
+	Encoding string
+	/*
+		GPGSignature is the armored signature from the gpgsig header, without the leading space
+		git puts on its continuation lines.
+	*/
+	GPGSignature string
+	/*
+		ExtraHeaders keeps the headers this package does not interpret, such as mergetag or
+		gpgsig-sha256, in their original order so the commit re-serializes to the same bytes.
+	*/
+	ExtraHeaders []CommitHeader
+	Message      string
+}
+
+// CommitHeader is a commit header kept verbatim. Multi-line values are stored
+// without the leading space git puts on continuation lines.
+type CommitHeader struct {
+	Key   string
+	Value string
+	/*
+		afterSignature records that the header followed gpgsig, as gpgsig-sha256 does.
+	*/
+	afterSignature bool
+}
+
+func (h CommitHeader) String() string {
+	return fmt.Sprintf("%s %s\n", h.Key, strings.ReplaceAll(h.Value, "\n", "\n "))
+}
+
+func (r *Repository) ReadCommit(commit string) (Commit, error) {
+	hash, err := r.resolveRef(commit)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	typ, body, err := r.readObject(hash)
+	if err != nil {
+		return Commit{}, err
+	}
+	if typ != "commit" {
+		return Commit{}, fmt.Errorf("expected %s to be a commit, got: %s", hash, typ)
+	}
+
+	return parseCommit(hash, body)
+}
+
+func (r *Repository) CommitTreeHash(commit string) (string, error) {
+	c, err := r.ReadCommit(commit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit %s: %w", commit, err)
+	}
+
+	return c.Tree, nil
+}
+
+// CommitParents returns the parents of a commit in the order they are
+// recorded. A root commit has none.
+func (r *Repository) CommitParents(commit string) ([]string, error) {
+	c, err := r.ReadCommit(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", commit, err)
+	}
+
+	return c.Parents, nil
+}
+
+// CommitTree writes a commit of tree with the given parents, in order, signed
+// by the default author and committer. A trailing newline is added to the
+// message when it is missing, like git does.
+func (r *Repository) CommitTree(tree string, parents []string, message string) (string, error) {
+	typ, _, err := r.readObject(tree)
+	if err != nil {
+		return "", fmt.Errorf("failed to read tree %s: %w", tree, err)
+	}
+	if typ != "tree" {
+		return "", fmt.Errorf("expected %s to be a tree, got: %s", tree, typ)
+	}
+
+	for _, parent := range parents {
+		typ, _, err := r.readObject(parent)
+		if err != nil {
+			return "", fmt.Errorf("failed to read parent %s: %w", parent, err)
+		}
+		if typ != "commit" {
+			return "", fmt.Errorf("expected parent %s to be a commit, got: %s", parent, typ)
+		}
+	}
+
+	author, err := r.defaultSignature("author")
+	if err != nil {
+		return "", err
+	}
+
+	committer, err := r.defaultSignature("committer")
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	commit := Commit{Tree: tree, Parents: parents, Author: author, Committer: committer, Message: message}
+	return r.WriteObject("commit", commit.Bytes())
+}
+
+// Bytes serializes the commit into the body of a commit object. Headers,
+// including unknown ones, are written in the order git stores them, so a
+// commit that was read and written again keeps its hash.
+func (c Commit) Bytes() []byte {
+	var body strings.Builder
+	fmt.Fprintf(&body, "tree %s\n", c.Tree)
+	for _, parent := range c.Parents {
+		fmt.Fprintf(&body, "parent %s\n", parent)
+	}
+	fmt.Fprintf(&body, "author %s\n", c.Author)
+	fmt.Fprintf(&body, "committer %s\n", c.Committer)
+	if c.Encoding != "" {
+		fmt.Fprintf(&body, "encoding %s\n", c.Encoding)
+	}
+	for _, header := range c.ExtraHeaders {
+		if !header.afterSignature {
+			body.WriteString(header.String())
+		}
+	}
+	if c.GPGSignature != "" {
+		body.WriteString(CommitHeader{Key: "gpgsig", Value: c.GPGSignature}.String())
+	}
+	for _, header := range c.ExtraHeaders {
+		if header.afterSignature {
+			body.WriteString(header.String())
+		}
+	}
+	body.WriteString("\n")
+	body.WriteString(c.Message)
+
+	return []byte(body.String())
+}
+
+func parseCommit(hash string, body []byte) (Commit, error) {
+	commit := Commit{Hash: hash}
+
+	headers, message, _ := strings.Cut(string(body), "\n\n")
+	commit.Message = message
+
+	var key string
+	for _, line := range strings.Split(headers, "\n") {
+		/*
+			Lines starting with a space continue the value of the previous header.
+		*/
+		if strings.HasPrefix(line, " ") {
+			switch {
+			case key == "gpgsig":
+				commit.GPGSignature += "\n" + line[1:]
+			case len(commit.ExtraHeaders) > 0 && commit.ExtraHeaders[len(commit.ExtraHeaders)-1].Key == key:
+				commit.ExtraHeaders[len(commit.ExtraHeaders)-1].Value += "\n" + line[1:]
+			}
+			continue
+		}
+
+		var value string
+		key, value, _ = strings.Cut(line, " ")
+
+		var err error
+		switch key {
+		case "tree":
+			commit.Tree = value
+		case "parent":
+			commit.Parents = append(commit.Parents, value)
+		case "author":
+			commit.Author, err = parseSignature(value)
+		case "committer":
+			commit.Committer, err = parseSignature(value)
+		case "encoding":
+			commit.Encoding = value
+		case "gpgsig":
+			commit.GPGSignature = value
+		default:
+			commit.ExtraHeaders = append(commit.ExtraHeaders, CommitHeader{Key: key, Value: value, afterSignature: commit.GPGSignature != ""})
+		}
+		if err != nil {
+			return Commit{}, fmt.Errorf("failed to parse %s of commit %s: %w", key, hash, err)
+		}
+	}
+
+	if commit.Tree == "" {
+		return Commit{}, fmt.Errorf("commit %s does not reference a tree", hash)
+	}
+
+	return commit, nil
+}
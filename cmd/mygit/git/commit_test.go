@@ -0,0 +1,297 @@
+package git_test
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestCommitTreeHash(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+
+	err := os.WriteFile(path.Join(root, "a.txt"), []byte("a"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	commitHash := runGit(t, root, "rev-parse", "HEAD")
+	expected := runGit(t, root, "rev-parse", "HEAD^{tree}")
+
+	repository := git.NewRepository(root)
+
+	for _, commit := range []string{commitHash, "HEAD", "main", "refs/heads/main"} {
+		t.Run("Resolves the tree of "+commit, func(t *testing.T) {
+			treeHash, err := repository.CommitTreeHash(commit)
+			if err != nil {
+				t.Fatalf("error reading tree hash: %v", err)
+			}
+
+			if treeHash != expected {
+				t.Fatalf("expected %s, got %s", expected, treeHash)
+			}
+		})
+	}
+
+	t.Run("Fails for an object that is not a commit", func(t *testing.T) {
+		_, err := repository.CommitTreeHash(expected)
+		if err == nil {
+			t.Fatalf("expected error reading the tree of a tree, got nil")
+		}
+	})
+}
+
+func TestCommitParents(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "root")
+	rootCommit := runGit(t, root, "rev-parse", "HEAD")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "child")
+	child := runGit(t, root, "rev-parse", "HEAD")
+
+	runGit(t, root, "checkout", "-q", "-b", "side", rootCommit)
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "side")
+	side := runGit(t, root, "rev-parse", "HEAD")
+	runGit(t, root, "checkout", "-q", "main")
+	runGit(t, root, "merge", "-q", "--no-ff", "-m", "merge", "side")
+	merge := runGit(t, root, "rev-parse", "HEAD")
+
+	repository := git.NewRepository(root)
+
+	tests := []struct {
+		name     string
+		commit   string
+		expected []string
+	}{
+		{name: "Returns no parents for a root commit", commit: rootCommit, expected: nil},
+		{name: "Returns the parent of a single-parent commit", commit: child, expected: []string{rootCommit}},
+		{name: "Returns the parents of a merge in order", commit: merge, expected: []string{child, side}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parents, err := repository.CommitParents(tt.commit)
+			if err != nil {
+				t.Fatalf("error reading parents: %v", err)
+			}
+
+			if len(parents) != len(tt.expected) || (len(parents) > 0 && !reflect.DeepEqual(parents, tt.expected)) {
+				t.Fatalf("expected %v, got %v", tt.expected, parents)
+			}
+		})
+	}
+}
+
+func TestCommitTree(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_AUTHOR_DATE", "1700000000 +0000")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_DATE", "1700000000 +0000")
+
+	tree := runGit(t, root, "write-tree")
+	first := runGit(t, root, "commit-tree", tree, "-m", "first")
+	second := runGit(t, root, "commit-tree", tree, "-m", "second")
+
+	repository := git.NewRepository(root)
+
+	t.Run("Writes a merge commit with parents in order", func(t *testing.T) {
+		hash, err := repository.CommitTree(tree, []string{second, first}, "merge\n\nof two commits")
+		if err != nil {
+			t.Fatalf("error writing commit: %v", err)
+		}
+
+		expected := runGit(t, root, "commit-tree", tree, "-p", second, "-p", first, "-m", "merge", "-m", "of two commits")
+		if hash != expected {
+			t.Fatalf("expected %s, got %s", expected, hash)
+		}
+
+		commit, err := repository.ReadCommit(hash)
+		if err != nil {
+			t.Fatalf("error reading commit: %v", err)
+		}
+
+		if !reflect.DeepEqual(commit.Parents, []string{second, first}) {
+			t.Fatalf("expected parents %v, got %v", []string{second, first}, commit.Parents)
+		}
+	})
+
+	t.Run("Fails for a parent that is not a commit", func(t *testing.T) {
+		_, err := repository.CommitTree(tree, []string{tree}, "broken")
+		if err == nil {
+			t.Fatalf("expected error committing with a tree as parent, got nil")
+		}
+	})
+}
+
+func TestCommitEncoding(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	runGit(t, root, "-c", "i18n.commitEncoding=ISO-8859-1", "commit", "-q", "--allow-empty", "-m", "latin-1 message")
+	hash := runGit(t, root, "rev-parse", "HEAD")
+
+	repository := git.NewRepository(root)
+	commit, err := repository.ReadCommit(hash)
+	if err != nil {
+		t.Fatalf("error reading commit: %v", err)
+	}
+
+	t.Run("Reads the encoding header", func(t *testing.T) {
+		if commit.Encoding != "ISO-8859-1" {
+			t.Fatalf("expected encoding ISO-8859-1, got %q", commit.Encoding)
+		}
+
+		if commit.Message != "latin-1 message\n" {
+			t.Fatalf("expected the message without the header, got %q", commit.Message)
+		}
+	})
+
+	t.Run("Round-trips the commit", func(t *testing.T) {
+		rewritten, err := git.HashObject("commit", commit.Bytes())
+		if err != nil {
+			t.Fatalf("error hashing commit: %v", err)
+		}
+
+		if rewritten != hash {
+			t.Fatalf("expected %s, got %s", hash, rewritten)
+		}
+	})
+}
+
+func TestCommitGPGSignature(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	repository := git.NewRepository(root)
+
+	signature := strings.Join([]string{
+		"-----BEGIN PGP SIGNATURE-----",
+		"",
+		"iQEzBAABCAAdFiEEexampleexampleexampleexampleexampleFAmVX0oAACgkQ",
+		"exampleexampleexampleexampleexampleexampleexampleexampleexample==",
+		"=AbCd",
+		"-----END PGP SIGNATURE-----",
+	}, "\n")
+
+	body := "tree " + git.EmptyTreeHash(git.ObjectFormatSHA1) + "\n" +
+		"author Test <test@example.com> 1700000000 +0000\n" +
+		"committer Test <test@example.com> 1700000000 +0000\n" +
+		"gpgsig " + strings.ReplaceAll(signature, "\n", "\n ") + "\n" +
+		"\n" +
+		"signed commit\n"
+
+	hash, err := repository.WriteObject("commit", []byte(body))
+	if err != nil {
+		t.Fatalf("error writing commit: %v", err)
+	}
+
+	commit, err := repository.ReadCommit(hash)
+	if err != nil {
+		t.Fatalf("error reading commit: %v", err)
+	}
+
+	t.Run("Reads the whole signature", func(t *testing.T) {
+		if commit.GPGSignature != signature {
+			t.Fatalf("expected signature %q, got %q", signature, commit.GPGSignature)
+		}
+
+		if commit.Message != "signed commit\n" || commit.Committer.Name != "Test" {
+			t.Fatalf("expected the headers after the signature to be intact, got %+v", commit)
+		}
+	})
+
+	t.Run("Round-trips the commit", func(t *testing.T) {
+		if string(commit.Bytes()) != body {
+			t.Fatalf("expected %q, got %q", body, commit.Bytes())
+		}
+
+		rewritten, err := git.HashObject("commit", commit.Bytes())
+		if err != nil {
+			t.Fatalf("error hashing commit: %v", err)
+		}
+
+		if rewritten != hash {
+			t.Fatalf("expected %s, got %s", hash, rewritten)
+		}
+
+		typ := runGit(t, root, "cat-file", "-t", hash)
+		if typ != "commit" {
+			t.Fatalf("expected git to read a commit, got %s", typ)
+		}
+	})
+}
+
+func TestCommitExtraHeaders(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	repository := git.NewRepository(root)
+
+	body := "tree " + git.EmptyTreeHash(git.ObjectFormatSHA1) + "\n" +
+		"parent 1111111111111111111111111111111111111111\n" +
+		"parent 2222222222222222222222222222222222222222\n" +
+		"author Test <test@example.com> 1700000000 +0000\n" +
+		"committer Test <test@example.com> 1700000000 +0000\n" +
+		"encoding ISO-8859-1\n" +
+		"mergetag object 2222222222222222222222222222222222222222\n" +
+		" type commit\n" +
+		" tag v1\n" +
+		" \n" +
+		" release\n" +
+		"gpgsig -----BEGIN PGP SIGNATURE-----\n" +
+		" sha1\n" +
+		" -----END PGP SIGNATURE-----\n" +
+		"gpgsig-sha256 -----BEGIN PGP SIGNATURE-----\n" +
+		" sha256\n" +
+		" -----END PGP SIGNATURE-----\n" +
+		"\n" +
+		"merge with extra headers\n"
+
+	hash, err := repository.WriteObject("commit", []byte(body))
+	if err != nil {
+		t.Fatalf("error writing commit: %v", err)
+	}
+
+	commit, err := repository.ReadCommit(hash)
+	if err != nil {
+		t.Fatalf("error reading commit: %v", err)
+	}
+
+	t.Run("Keeps unknown headers in order", func(t *testing.T) {
+		var keys []string
+		for _, header := range commit.ExtraHeaders {
+			keys = append(keys, header.Key)
+		}
+
+		if !reflect.DeepEqual(keys, []string{"mergetag", "gpgsig-sha256"}) {
+			t.Fatalf("expected mergetag and gpgsig-sha256, got %v", keys)
+		}
+
+		expected := "object 2222222222222222222222222222222222222222\ntype commit\ntag v1\n\nrelease"
+		if commit.ExtraHeaders[0].Value != expected {
+			t.Fatalf("expected mergetag %q, got %q", expected, commit.ExtraHeaders[0].Value)
+		}
+	})
+
+	t.Run("Round-trips the commit", func(t *testing.T) {
+		if string(commit.Bytes()) != body {
+			t.Fatalf("expected %q, got %q", body, commit.Bytes())
+		}
+
+		rewritten, err := git.HashObject("commit", commit.Bytes())
+		if err != nil {
+			t.Fatalf("error hashing commit: %v", err)
+		}
+
+		if rewritten != hash {
+			t.Fatalf("expected %s, got %s", hash, rewritten)
+		}
+	})
+}
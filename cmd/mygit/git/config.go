@@ -0,0 +1,236 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigValue reads key, such as user.name, from the repository config. The
+// boolean reports whether the key is set at all.
+func (r *Repository) ConfigValue(key string) (string, bool, error) {
+	return r.configValue(key)
+}
+
+func (r *Repository) configValue(key string) (string, bool, error) {
+	return readConfigValue(r.configPath(), key)
+}
+
+// userConfigValue reads key from the repository config and, when it is not set
+// there, from the global config, the way git resolves user.name and friends.
+func (r *Repository) userConfigValue(key string) (string, bool, error) {
+	value, found, err := r.configValue(key)
+	if err != nil || found {
+		return value, found, err
+	}
+
+	return readConfigValue(globalConfigPath(), key)
+}
+
+// globalConfigPath is the per-user config file: GIT_CONFIG_GLOBAL when set,
+// otherwise .gitconfig in the home directory.
+func globalConfigPath() string {
+	if p := os.Getenv("GIT_CONFIG_GLOBAL"); p != "" {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".gitconfig")
+}
+
+func readConfigValue(configPath string, key string) (string, bool, error) {
+	section, name, err := splitConfigKey(key)
+	if err != nil {
+		return "", false, err
+	}
+
+	if configPath == "" {
+		return "", false, nil
+	}
+
+	file, err := os.Open(configPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, nil
+		}
+
+		return "", false, fmt.Errorf("failed to open config: %w", err)
+	}
+	defer file.Close()
+
+	var value string
+	var found bool
+	currentSection := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			currentSection = parseConfigSection(line[1 : len(line)-1])
+			continue
+		}
+
+		if currentSection != section {
+			continue
+		}
+
+		k, v, hasValue := strings.Cut(line, "=")
+		if strings.ToLower(strings.TrimSpace(k)) != name {
+			continue
+		}
+
+		/*
+			A key without a value is a boolean true. Later entries override earlier ones.
+		*/
+		value, found = "true", true
+		if hasValue {
+			value = strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return value, found, nil
+}
+
+// configBool reads a boolean key the way git does, treating a missing key as
+// false.
+func (r *Repository) configBool(key string) (bool, error) {
+	value, found, err := r.configValue(key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	default:
+		return false, fmt.Errorf("bad boolean config value %q for %s", value, key)
+	}
+}
+
+// SetConfigValue sets key in the repository config, replacing its last
+// occurrence or adding it to the end of its section, which is created if it
+// does not exist yet.
+func (r *Repository) SetConfigValue(key, value string) error {
+	section, name, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+
+	contents, err := os.ReadFile(r.configPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var lines []string
+	if len(contents) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	}
+
+	sectionEnd, keyLine := -1, -1
+	currentSection := ""
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			currentSection = parseConfigSection(line[1 : len(line)-1])
+			if currentSection == section {
+				sectionEnd = i + 1
+			}
+			continue
+		}
+
+		if currentSection != section {
+			continue
+		}
+
+		sectionEnd = i + 1
+		k, _, _ := strings.Cut(line, "=")
+		if strings.ToLower(strings.TrimSpace(k)) == name {
+			keyLine = i
+		}
+	}
+
+	entry := fmt.Sprintf("\t%s = %s", name, formatConfigValue(value))
+	switch {
+	case keyLine >= 0:
+		lines[keyLine] = entry
+	case sectionEnd >= 0:
+		lines = append(lines[:sectionEnd], append([]string{entry}, lines[sectionEnd:]...)...)
+	default:
+		lines = append(lines, formatConfigSection(section), entry)
+	}
+
+	err = os.WriteFile(r.configPath(), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
+}
+
+// formatConfigValue quotes values whose surrounding whitespace or comment
+// characters would otherwise be lost when read back.
+func formatConfigValue(value string) string {
+	if strings.TrimSpace(value) != value || strings.ContainsAny(value, "#;") {
+		return `"` + value + `"`
+	}
+
+	return value
+}
+
+// formatConfigSection turns `remote.origin` into `[remote "origin"]`.
+func formatConfigSection(section string) string {
+	name, subsection, found := strings.Cut(section, ".")
+	if !found {
+		return "[" + name + "]"
+	}
+
+	return fmt.Sprintf("[%s %q]", name, subsection)
+}
+
+// parseConfigSection turns `remote "origin"` into `remote.origin`.
+// Section names are case-insensitive, subsection names are not.
+func parseConfigSection(header string) string {
+	name, subsection, found := strings.Cut(strings.TrimSpace(header), " ")
+	name = strings.ToLower(name)
+	if !found {
+		return name
+	}
+
+	return name + "." + strings.Trim(strings.TrimSpace(subsection), `"`)
+}
+
+// splitConfigKey turns `remote.origin.url` into the section `remote.origin` and the name `url`.
+func splitConfigKey(key string) (string, string, error) {
+	i := strings.LastIndex(key, ".")
+	if i <= 0 || i == len(key)-1 {
+		return "", "", fmt.Errorf("key %s does not contain a section", key)
+	}
+
+	section, name := key[:i], key[i+1:]
+	sectionName, subsection, found := strings.Cut(section, ".")
+	section = strings.ToLower(sectionName)
+	if found {
+		section += "." + subsection
+	}
+
+	return section, strings.ToLower(name), nil
+}
@@ -0,0 +1,173 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestConfig(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	repository := git.NewRepository(root)
+
+	t.Run("Sets values git can read", func(t *testing.T) {
+		err := repository.SetConfigValue("user.name", "Config User")
+		if err != nil {
+			t.Fatalf("error setting user.name: %v", err)
+		}
+
+		err = repository.SetConfigValue("user.email", "config@example.com")
+		if err != nil {
+			t.Fatalf("error setting user.email: %v", err)
+		}
+
+		err = repository.SetConfigValue("remote.origin.url", "https://example.com/repo.git")
+		if err != nil {
+			t.Fatalf("error setting remote.origin.url: %v", err)
+		}
+
+		expected := map[string]string{
+			"user.name":         "Config User",
+			"user.email":        "config@example.com",
+			"remote.origin.url": "https://example.com/repo.git",
+			"core.bare":         "false",
+		}
+		for key, value := range expected {
+			if got := runGit(t, root, "config", key); got != value {
+				t.Fatalf("expected %s to be %q, got %q", key, value, got)
+			}
+		}
+	})
+
+	t.Run("Replaces existing values", func(t *testing.T) {
+		err := repository.SetConfigValue("user.name", "Renamed User")
+		if err != nil {
+			t.Fatalf("error setting user.name: %v", err)
+		}
+
+		value, found, err := repository.ConfigValue("user.name")
+		if err != nil {
+			t.Fatalf("error reading user.name: %v", err)
+		}
+
+		if !found || value != "Renamed User" {
+			t.Fatalf("expected user.name to be %q, got %q", "Renamed User", value)
+		}
+
+		values := runGit(t, root, "config", "--get-all", "user.name")
+		if values != "Renamed User" {
+			t.Fatalf("expected a single user.name entry, got %q", values)
+		}
+	})
+
+	t.Run("Reads the user identity", func(t *testing.T) {
+		identity, err := repository.UserIdentity()
+		if err != nil {
+			t.Fatalf("error reading identity: %v", err)
+		}
+
+		if identity.Name != "Renamed User" || identity.Email != "config@example.com" {
+			t.Fatalf("expected Renamed User <config@example.com>, got %s <%s>", identity.Name, identity.Email)
+		}
+	})
+
+	t.Run("Reports missing keys", func(t *testing.T) {
+		_, found, err := repository.ConfigValue("user.signingkey")
+		if err != nil {
+			t.Fatalf("error reading user.signingkey: %v", err)
+		}
+
+		if found {
+			t.Fatalf("expected user.signingkey to be unset")
+		}
+	})
+}
+
+func TestUnknownIdentity(t *testing.T) {
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "")
+	}
+
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	_, err = repository.UserIdentity()
+	if !errors.Is(err, git.ErrIdentityUnknown) {
+		t.Fatalf("expected error %v, got %v", git.ErrIdentityUnknown, err)
+	}
+
+	tree, err := repository.WriteObject("tree", nil)
+	if err != nil {
+		t.Fatalf("error writing tree: %v", err)
+	}
+
+	_, err = repository.CommitTree(tree, nil, "initial")
+	if !errors.Is(err, git.ErrIdentityUnknown) {
+		t.Fatalf("expected committing to fail with %v, got %v", git.ErrIdentityUnknown, err)
+	}
+}
+
+func TestGlobalIdentity(t *testing.T) {
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "")
+	}
+
+	globalConfig := filepath.Join(t.TempDir(), "gitconfig")
+	err := os.WriteFile(globalConfig, []byte("[user]\n\tname = Global User\n\temail = global@example.com\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing global config: %v", err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
+
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err = repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	t.Run("Falls back to the global config", func(t *testing.T) {
+		tree, err := repository.WriteObject("tree", nil)
+		if err != nil {
+			t.Fatalf("error writing tree: %v", err)
+		}
+
+		hash, err := repository.CommitTree(tree, nil, "initial")
+		if err != nil {
+			t.Fatalf("error committing: %v", err)
+		}
+
+		commit, err := repository.ReadCommit(hash)
+		if err != nil {
+			t.Fatalf("error reading commit: %v", err)
+		}
+
+		if commit.Author.Name != "Global User" || commit.Committer.Email != "global@example.com" {
+			t.Fatalf("expected the global identity, got %s <%s>", commit.Author.Name, commit.Committer.Email)
+		}
+	})
+
+	t.Run("Prefers the repository config", func(t *testing.T) {
+		err := repository.SetConfigValue("user.name", "Local User")
+		if err != nil {
+			t.Fatalf("error setting user.name: %v", err)
+		}
+
+		identity, err := repository.UserIdentity()
+		if err != nil {
+			t.Fatalf("error reading identity: %v", err)
+		}
+
+		if identity.Name != "Local User" || identity.Email != "global@example.com" {
+			t.Fatalf("expected Local User <global@example.com>, got %s <%s>", identity.Name, identity.Email)
+		}
+	})
+}
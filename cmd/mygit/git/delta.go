@@ -0,0 +1,186 @@
+package git
+
+const (
+	deltaBlockSize  = 16
+	deltaWindowSize = 10
+	maxDeltaInsert  = 0x7f
+	maxDeltaCopy    = 0xffffff
+)
+
+type deltaBase struct {
+	hash string
+	body []byte
+}
+
+// deltaBases remembers recently packed blobs as candidates for delta bases:
+// the last blob seen at every path and a small window of the latest blobs.
+type deltaBases struct {
+	byPath map[string]deltaBase
+	window []deltaBase
+}
+
+func newDeltaBases() *deltaBases {
+	return &deltaBases{byPath: map[string]deltaBase{}}
+}
+
+func (d *deltaBases) add(object PackObject, body []byte) {
+	base := deltaBase{hash: object.Hash, body: body}
+	if object.Path != "" {
+		d.byPath[object.Path] = base
+	}
+
+	d.window = append(d.window, base)
+	if len(d.window) > deltaWindowSize {
+		d.window = d.window[1:]
+	}
+}
+
+// find picks a base for body and returns it with the delta, or an empty hash
+// when no candidate produces a delta smaller than the body itself.
+func (d *deltaBases) find(object PackObject, body []byte) (string, []byte) {
+	var candidate *deltaBase
+	if base, ok := d.byPath[object.Path]; ok && object.Path != "" {
+		candidate = &base
+	} else {
+		/*
+			Without a shared path, the blob closest in size is the most likely relative.
+		*/
+		for i := range d.window {
+			base := &d.window[i]
+			if candidate == nil || sizeDistance(base.body, body) < sizeDistance(candidate.body, body) {
+				candidate = base
+			}
+		}
+	}
+
+	if candidate == nil || candidate.hash == object.Hash {
+		return "", nil
+	}
+
+	if len(candidate.body) > 2*len(body) || len(body) > 2*len(candidate.body) {
+		return "", nil
+	}
+
+	delta := encodeDelta(candidate.body, body)
+	if len(delta) >= len(body) {
+		return "", nil
+	}
+
+	return candidate.hash, delta
+}
+
+func sizeDistance(a, b []byte) int {
+	if len(a) > len(b) {
+		return len(a) - len(b)
+	}
+
+	return len(b) - len(a)
+}
+
+// encodeDelta builds a git delta turning base into target: both sizes as
+// varints, then copy instructions for blocks found in base and insert
+// instructions for everything else.
+func encodeDelta(base, target []byte) []byte {
+	delta := appendDeltaSize(nil, len(base))
+	delta = appendDeltaSize(delta, len(target))
+
+	index := map[string]int{}
+	for i := 0; i+deltaBlockSize <= len(base); i += deltaBlockSize {
+		block := string(base[i : i+deltaBlockSize])
+		if _, ok := index[block]; !ok {
+			index[block] = i
+		}
+	}
+
+	var pending []byte
+	for i := 0; i < len(target); {
+		offset, ok := -1, false
+		if i+deltaBlockSize <= len(target) {
+			offset, ok = index[string(target[i:i+deltaBlockSize])]
+		}
+
+		if !ok {
+			pending = append(pending, target[i])
+			i++
+			continue
+		}
+
+		/*
+			Grow the match backwards into the pending insert, then forwards as far as it goes.
+		*/
+		for len(pending) > 0 && offset > 0 && base[offset-1] == pending[len(pending)-1] {
+			pending = pending[:len(pending)-1]
+			offset--
+			i--
+		}
+
+		length := 0
+		for offset+length < len(base) && i+length < len(target) && base[offset+length] == target[i+length] {
+			length++
+		}
+
+		delta = appendDeltaInsert(delta, pending)
+		pending = nil
+		delta = appendDeltaCopy(delta, offset, length)
+		i += length
+	}
+
+	return appendDeltaInsert(delta, pending)
+}
+
+func appendDeltaSize(delta []byte, size int) []byte {
+	for size >= 0x80 {
+		delta = append(delta, byte(size&0x7f)|0x80)
+		size >>= 7
+	}
+
+	return append(delta, byte(size))
+}
+
+func appendDeltaInsert(delta []byte, data []byte) []byte {
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxDeltaInsert {
+			n = maxDeltaInsert
+		}
+
+		delta = append(delta, byte(n))
+		delta = append(delta, data[:n]...)
+		data = data[n:]
+	}
+
+	return delta
+}
+
+// appendDeltaCopy encodes a copy as a command byte whose low bits flag which
+// offset and size bytes follow; zero bytes are left out.
+func appendDeltaCopy(delta []byte, offset, length int) []byte {
+	for length > 0 {
+		n := length
+		if n > maxDeltaCopy {
+			n = maxDeltaCopy
+		}
+
+		command := byte(0x80)
+		var args []byte
+		for i := 0; i < 4; i++ {
+			if b := byte(offset >> (8 * i)); b != 0 {
+				command |= 1 << i
+				args = append(args, b)
+			}
+		}
+		for i := 0; i < 3; i++ {
+			if b := byte(n >> (8 * i)); b != 0 {
+				command |= 1 << (4 + i)
+				args = append(args, b)
+			}
+		}
+
+		delta = append(delta, command)
+		delta = append(delta, args...)
+		offset += n
+		length -= n
+	}
+
+	return delta
+}
@@ -0,0 +1,170 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "A"
+	ChangeModified ChangeType = "M"
+	ChangeDeleted  ChangeType = "D"
+)
+
+// Change describes one file that differs between two trees. The old side is
+// empty for additions and the new side for deletions.
+type Change struct {
+	Type    ChangeType
+	Path    string
+	OldMode string
+	OldHash string
+	NewMode string
+	NewHash string
+}
+
+// DiffTrees lists the files that differ between two trees, recursing into
+// subtrees, sorted by path. An empty hash stands for the empty tree.
+func (r *Repository) DiffTrees(oldTree, newTree string) ([]Change, error) {
+	var changes []Change
+	err := r.diffTrees(oldTree, newTree, "", &changes)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// CommitChanges diffs a commit against its first parent, or against the empty
+// tree for a root commit.
+func (r *Repository) CommitChanges(commit string) ([]Change, error) {
+	c, err := r.ReadCommit(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", commit, err)
+	}
+
+	parentTree := ""
+	if len(c.Parents) > 0 {
+		parentTree, err = r.CommitTreeHash(c.Parents[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r.DiffTrees(parentTree, c.Tree)
+}
+
+func (r *Repository) diffTrees(oldTree, newTree, prefix string, changes *[]Change) error {
+	if oldTree == newTree {
+		return nil
+	}
+
+	oldEntries, err := r.treeEntriesByName(oldTree)
+	if err != nil {
+		return err
+	}
+
+	newEntries, err := r.treeEntriesByName(newTree)
+	if err != nil {
+		return err
+	}
+
+	for name, oldEntry := range oldEntries {
+		entryPath := path.Join(prefix, name)
+		newEntry, ok := newEntries[name]
+		if !ok {
+			err = r.diffEntries(&oldEntry, nil, entryPath, changes)
+		} else {
+			err = r.diffEntries(&oldEntry, &newEntry, entryPath, changes)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, newEntry := range newEntries {
+		if _, ok := oldEntries[name]; ok {
+			continue
+		}
+
+		err = r.diffEntries(nil, &newEntry, path.Join(prefix, name), changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffEntries compares two entries at the same path, either of which may be
+// missing. A file replaced by a directory shows up as a deletion and additions.
+func (r *Repository) diffEntries(oldEntry, newEntry *TreeEntry, entryPath string, changes *[]Change) error {
+	oldIsTree := oldEntry != nil && oldEntry.Mode == "40000"
+	newIsTree := newEntry != nil && newEntry.Mode == "40000"
+
+	if oldIsTree || newIsTree {
+		oldTree, newTree := "", ""
+		if oldIsTree {
+			oldTree = oldEntry.Hash
+		}
+		if newIsTree {
+			newTree = newEntry.Hash
+		}
+
+		err := r.diffTrees(oldTree, newTree, entryPath, changes)
+		if err != nil {
+			return err
+		}
+
+		if oldEntry != nil && !oldIsTree {
+			*changes = append(*changes, Change{Type: ChangeDeleted, Path: entryPath, OldMode: oldEntry.Mode, OldHash: oldEntry.Hash})
+		}
+		if newEntry != nil && !newIsTree {
+			*changes = append(*changes, Change{Type: ChangeAdded, Path: entryPath, NewMode: newEntry.Mode, NewHash: newEntry.Hash})
+		}
+
+		return nil
+	}
+
+	switch {
+	case newEntry == nil:
+		*changes = append(*changes, Change{Type: ChangeDeleted, Path: entryPath, OldMode: oldEntry.Mode, OldHash: oldEntry.Hash})
+	case oldEntry == nil:
+		*changes = append(*changes, Change{Type: ChangeAdded, Path: entryPath, NewMode: newEntry.Mode, NewHash: newEntry.Hash})
+	case oldEntry.Hash != newEntry.Hash || oldEntry.Mode != newEntry.Mode:
+		*changes = append(*changes, Change{
+			Type:    ChangeModified,
+			Path:    entryPath,
+			OldMode: oldEntry.Mode,
+			OldHash: oldEntry.Hash,
+			NewMode: newEntry.Mode,
+			NewHash: newEntry.Hash,
+		})
+	}
+
+	return nil
+}
+
+func (r *Repository) treeEntriesByName(tree string) (map[string]TreeEntry, error) {
+	entries := map[string]TreeEntry{}
+	if tree == "" {
+		return entries, nil
+	}
+
+	list, err := r.readTreeEntries(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree %s: %w", tree, err)
+	}
+
+	for _, entry := range list {
+		entries[entry.Name] = entry
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,87 @@
+package git_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestCommitChanges(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	write := func(name, contents string) {
+		t.Helper()
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	write("modified.txt", "before")
+	write("removed.txt", "gone soon")
+	write("dir/kept.txt", "kept")
+	write("becomes-dir", "a file for now")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	write("modified.txt", "after")
+	write("dir/nested/added.txt", "new")
+	os.Remove(path.Join(root, "removed.txt"))
+	os.Remove(path.Join(root, "becomes-dir"))
+	write("becomes-dir/inside.txt", "now a directory")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "change things")
+
+	repository := git.NewRepository(root)
+
+	format := func(changes []git.Change) string {
+		var lines []string
+		for _, change := range changes {
+			lines = append(lines, string(change.Type)+"\t"+change.Path)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	t.Run("Diffs a commit against its parent", func(t *testing.T) {
+		changes, err := repository.CommitChanges("HEAD")
+		if err != nil {
+			t.Fatalf("error listing changes: %v", err)
+		}
+
+		expected := strings.Join([]string{
+			"D\tbecomes-dir",
+			"A\tbecomes-dir/inside.txt",
+			"A\tdir/nested/added.txt",
+			"M\tmodified.txt",
+			"D\tremoved.txt",
+		}, "\n")
+		if got := format(changes); got != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, got)
+		}
+
+		if got := runGit(t, root, "diff-tree", "-r", "--no-commit-id", "--name-status", "HEAD"); format(changes) != got {
+			t.Fatalf("expected to match git:\n%s\ngot:\n%s", got, format(changes))
+		}
+	})
+
+	t.Run("Diffs a root commit against the empty tree", func(t *testing.T) {
+		changes, err := repository.CommitChanges(runGit(t, root, "rev-parse", "HEAD~1"))
+		if err != nil {
+			t.Fatalf("error listing changes: %v", err)
+		}
+
+		expected := runGit(t, root, "diff-tree", "-r", "--root", "--no-commit-id", "--name-status", "HEAD~1")
+		if got := format(changes); got != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, got)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const ErrUnsafePath = Error("unsafe path")
+
+func (r *Repository) ExtractTree(treeHash string, dir string) error {
+	entries, err := r.readTreeEntries(treeHash)
+	if err != nil {
+		return fmt.Errorf("failed to read tree %s: %w", treeHash, err)
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create the directory: %w", err)
+	}
+
+	/*
+		Symlinks are created last, so nothing extracted after them can be written through one.
+	*/
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Mode != "120000" && entries[j].Mode == "120000"
+	})
+
+	for _, entry := range entries {
+		target, err := safeJoin(dir, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		switch entry.Mode {
+		case "40000":
+			err = r.ExtractTree(entry.Hash, target)
+			if err != nil {
+				return err
+			}
+
+		case "160000":
+			err = os.MkdirAll(target, 0755)
+			if err != nil {
+				return fmt.Errorf("failed to create the directory: %w", err)
+			}
+
+		case "120000":
+			_, linkTarget, err := r.readObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", entry.Name, err)
+			}
+
+			err = os.Symlink(string(linkTarget), target)
+			if err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", target, err)
+			}
+
+		case "100644", "100755":
+			_, contents, err := r.readObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to read blob %s: %w", entry.Name, err)
+			}
+
+			perm := os.FileMode(0644)
+			if entry.Mode == "100755" {
+				perm = 0755
+			}
+
+			err = os.WriteFile(target, contents, perm)
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", target, err)
+			}
+
+			/*
+				WriteFile only applies the permissions when it creates the file.
+			*/
+			err = os.Chmod(target, perm)
+			if err != nil {
+				return fmt.Errorf("failed to set the mode of %s: %w", target, err)
+			}
+
+		default:
+			return fmt.Errorf("unsupported mode %s for entry %s", entry.Mode, entry.Name)
+		}
+	}
+
+	return nil
+}
+
+// safeJoin joins a tree entry name onto base, rejecting names that would escape
+// base or write into the repository's own .git directory. An entry names a
+// single path component, so any separator is refused: otherwise "link/x" could
+// write through a symlink called link.
+func safeJoin(base, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.EqualFold(name, ".git") ||
+		strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+
+	cleanBase := filepath.Clean(base)
+	joined := filepath.Join(cleanBase, name)
+	if joined != cleanBase && !strings.HasPrefix(joined, cleanBase+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+
+	return joined, nil
+}
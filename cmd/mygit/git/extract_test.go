@@ -0,0 +1,170 @@
+package git_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestExtractTree(t *testing.T) {
+	t.Run("Materializes files, modes and directories", func(t *testing.T) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q")
+
+		files := map[string]os.FileMode{
+			"a.txt":            0644,
+			"run.sh":           0755,
+			"dir/nested/b.txt": 0644,
+		}
+		for name, mode := range files {
+			err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+			if err != nil {
+				t.Fatalf("error creating directory: %v", err)
+			}
+
+			err = os.WriteFile(path.Join(root, name), []byte(name), mode)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+		}
+		runGit(t, root, "add", ".")
+		treeHash := runGit(t, root, "write-tree")
+
+		repository := git.NewRepository(root)
+		out := t.TempDir()
+		err := repository.ExtractTree(treeHash, out)
+		if err != nil {
+			t.Fatalf("error extracting tree: %v", err)
+		}
+
+		for name, mode := range files {
+			fi, err := os.Stat(path.Join(out, name))
+			if err != nil {
+				t.Fatalf("error stating %s: %v", name, err)
+			}
+
+			if fi.Mode().Perm() != mode {
+				t.Fatalf("expected %s to have mode %v, got %v", name, mode, fi.Mode().Perm())
+			}
+		}
+	})
+
+	for _, name := range []string{"../../etc/x", "..", "/etc/x", ".git", "dir/../../x"} {
+		t.Run("Rejects the crafted entry name "+name, func(t *testing.T) {
+			root := t.TempDir()
+			repository := git.NewRepository(root)
+			_, err := repository.Init()
+			if err != nil {
+				t.Fatalf("error initializing repository: %v", err)
+			}
+
+			blobHash := writeRawObject(t, root, "blob", []byte("pwned"))
+			rawHash, err := hex.DecodeString(blobHash)
+			if err != nil {
+				t.Fatalf("error decoding hash: %v", err)
+			}
+			treeHash := writeRawObject(t, root, "tree", append([]byte("100644 "+name+"\x00"), rawHash...))
+
+			out := path.Join(t.TempDir(), "a", "b")
+			err = repository.ExtractTree(treeHash, out)
+			if !errors.Is(err, git.ErrUnsafePath) {
+				t.Fatalf("expected error %v, got %v", git.ErrUnsafePath, err)
+			}
+
+			_, err = os.Stat(path.Join(out, "../../etc/x"))
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("expected nothing to be written outside the target, got %v", err)
+			}
+		})
+	}
+
+	t.Run("Never writes through a symlink from the same tree", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		outside := t.TempDir()
+		rawHash := func(hash string) []byte {
+			raw, err := hex.DecodeString(hash)
+			if err != nil {
+				t.Fatalf("error decoding hash: %v", err)
+			}
+			return raw
+		}
+		linkHash := writeRawObject(t, root, "blob", []byte(outside))
+		fileLinkHash := writeRawObject(t, root, "blob", []byte(path.Join(outside, "x")))
+		blobHash := writeRawObject(t, root, "blob", []byte("pwned"))
+
+		trees := map[string][]byte{
+			"a nested entry": append(append(append([]byte("120000 link\x00"), rawHash(linkHash)...),
+				[]byte("100644 link/x\x00")...), rawHash(blobHash)...),
+			"a repeated entry": append(append(append([]byte("120000 x\x00"), rawHash(fileLinkHash)...),
+				[]byte("100644 x\x00")...), rawHash(blobHash)...),
+		}
+		for name, body := range trees {
+			treeHash := writeRawObject(t, root, "tree", body)
+
+			err = repository.ExtractTree(treeHash, path.Join(t.TempDir(), "out"))
+			if err == nil {
+				t.Fatalf("expected error extracting %s, got nil", name)
+			}
+
+			_, err = os.Stat(path.Join(outside, "x"))
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("expected nothing to be written outside the target through %s, got %v", name, err)
+			}
+		}
+	})
+}
+
+func TestRoundTripTree(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	files := map[string]os.FileMode{
+		"a.txt":              0644,
+		"bin/run.sh":         0755,
+		"dir/nested/b.txt":   0644,
+		"dir/nested/deep/c":  0755,
+		"dir/other/d.txt":    0644,
+		"dir/other/e.config": 0644,
+	}
+	for name, mode := range files {
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(name+"\n"), mode)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	for link, target := range map[string]string{"link": "a.txt", "dir/nested/up": "../other/d.txt"} {
+		err := os.Symlink(target, path.Join(root, link))
+		if err != nil {
+			t.Fatalf("error creating symlink: %v", err)
+		}
+	}
+
+	stable, err := repository.RoundTripTree(root)
+	if err != nil {
+		t.Fatalf("error round-tripping tree: %v", err)
+	}
+
+	if !stable {
+		t.Fatalf("expected the tree hash to survive the round trip")
+	}
+}
@@ -2,8 +2,11 @@ package git
 
 import (
 	"bufio"
+	"bytes"
 	"compress/zlib"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -11,8 +14,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 /*
@@ -28,273 +34,876 @@ func (e Error) Error() string {
 const (
 	ErrRepositoryAlreadyInitialized = Error("repository already initialized")
 	ErrInvalidHash                  = Error("invalid hash")
+	ErrInvalidObjectType            = Error("invalid object type")
+	ErrObjectTooLarge               = Error("object too large")
+	ErrHashMismatch                 = Error("hash mismatch")
+	ErrUnexpectedObjectType         = Error("unexpected object type")
 )
 
+const defaultMaxObjectSize = 512 << 20
+
+type TreeEntry struct {
+	Mode string
+	Name string
+	Hash string
+}
+
 type Repository struct {
+	/*
+		RawMode stores loose objects without zlib compression, which real git cannot read.
+		It exists for inspecting objects with external tools while debugging.
+	*/
+	RawMode bool
+	/*
+		MaxObjectSize caps the declared size of objects read into memory, guarding against
+		corrupt or hostile headers. Zero means the default of 512 MiB.
+	*/
+	MaxObjectSize int64
+	/*
+		RollbackOnError makes WriteTree delete the objects it created when it fails partway,
+		so no orphans are left behind. Objects that were already stored are kept.
+	*/
+	RollbackOnError bool
+	/*
+		DefaultBranch is the branch HEAD points at after Init. When empty, init.defaultBranch
+		from the global config is used, and failing that master.
+	*/
+	DefaultBranch string
+
 	root        string
+	bare        bool
 	initialized bool
+	storage     Storage
 }
 
 func NewRepository(root string) Repository {
-	return Repository{root: root}
+	r := Repository{root: absPath(root)}
+	r.storage = &fileStorage{gitDir: r.gitDir(), objectsDir: r.objectsDir(), alternates: r.alternateObjectsDirs()}
+	return r
 }
 
-func (r *Repository) Init() (func() error, error) {
-	cleanup := func() error {
-		err := os.RemoveAll(path.Join(r.root, ".git"))
-		if err != nil {
-			return fmt.Errorf("error cleaning up: %w", err)
-		}
+func NewBareRepository(root string) Repository {
+	r := Repository{root: absPath(root), bare: true}
+	r.storage = &fileStorage{gitDir: r.gitDir(), objectsDir: r.objectsDir(), alternates: r.alternateObjectsDirs(), bare: true}
+	return r
+}
 
-		return nil
-	}
+func NewRepositoryWithStorage(root string, storage Storage) Repository {
+	return Repository{root: absPath(root), storage: storage}
+}
 
-	if r.initialized {
-		return cleanup, ErrRepositoryAlreadyInitialized
+func (r *Repository) Init() (func() error, error) {
+	if r.initialized || r.storage.Exists() {
+		/*
+			The repository isn't ours to clean up, so callers deferring cleanup must not remove it.
+		*/
+		return func() error { return nil }, ErrRepositoryAlreadyInitialized
 	}
 
-	dirs := []string{
-		path.Join(r.root, ".git"),
-		path.Join(r.root, ".git/objects"),
-		path.Join(r.root, ".git/refs"),
+	head, err := r.initialHead()
+	if err != nil {
+		return func() error { return nil }, err
 	}
-	for _, dir := range dirs {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return cleanup, fmt.Errorf("error creating directory %s: %w", dir, err)
-		}
 
+	cleanup := r.storage.Destroy
+
+	err = r.storage.Init()
+	if err != nil {
+		return cleanup, err
 	}
 
-	filePath := path.Join(r.root, ".git/HEAD")
-	headFileContents := []byte("ref: refs/heads/master\n")
-	err := os.WriteFile(filePath, headFileContents, 0644)
+	err = r.storage.WriteRef("HEAD", head)
 	if err != nil {
-		return cleanup, fmt.Errorf("error writing to file %s: %w", filePath, err)
+		return cleanup, err
 	}
 
 	r.initialized = true
 	return cleanup, err
 }
 
-func (r *Repository) CatFile(hash string) (string, error) {
-	isValid := len([]byte(hash)) == 40
-	if !isValid {
-		return "", fmt.Errorf("%w expected 40 characters, got: %d", ErrInvalidHash, len(hash))
+// Reinit repairs an existing repository the way `git init` does when run twice:
+// missing directories and HEAD are recreated, everything else is left alone.
+func (r *Repository) Reinit() error {
+	err := r.storage.Init()
+	if err != nil {
+		return err
 	}
 
-	blobPath := path.Join(r.root, ".git", "objects", hash[:2], hash[2:])
-	blobFile, err := os.Open(blobPath)
+	_, err = r.storage.ReadRef("HEAD")
+	if errors.Is(err, ErrRefNotFound) {
+		var head string
+		head, err = r.initialHead()
+		if err == nil {
+			err = r.storage.WriteRef("HEAD", head)
+		}
+	}
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return "", fmt.Errorf("object: %s does not exist", blobPath)
+		return err
+	}
+
+	r.initialized = true
+	return nil
+}
+
+// initialHead returns what HEAD of a new repository holds: a symbolic ref to
+// the default branch.
+func (r *Repository) initialHead() (string, error) {
+	branch := r.DefaultBranch
+	if branch == "" {
+		value, found, err := readConfigValue(globalConfigPath(), "init.defaultBranch")
+		if err != nil {
+			return "", err
 		}
 
-		return "", fmt.Errorf("failed to open file: %w", err)
+		branch = "master"
+		if found {
+			branch = value
+		}
+	}
+
+	err := ValidateBranchName(branch)
+	if err != nil {
+		return "", err
+	}
+
+	return "ref: " + branchPrefix + branch, nil
+}
+
+func (r *Repository) CatFile(rev string) (string, error) {
+	hash, err := r.RevParse(rev)
+	if err != nil {
+		return "", err
 	}
-	defer blobFile.Close()
 
-	reader, err := zlib.NewReader(blobFile)
+	typ, body, err := r.readObject(hash)
 	if err != nil {
-		return "", fmt.Errorf("failed to read the contents: %w", err)
+		return "", err
+	}
+
+	/*
+		Tree bodies are binary, so they are printed in ls-tree format like git cat-file -p does.
+	*/
+	if typ == "tree" {
+		return r.ListTree(hash)
 	}
-	defer reader.Close()
 
-	blob, err := ioutil.ReadAll(reader)
+	return string(body), nil
+}
+
+// CatBlob returns the contents of a blob. Unlike CatFile it refuses trees,
+// commits and tags instead of formatting them.
+func (r *Repository) CatBlob(hash string) ([]byte, error) {
+	typ, body, err := r.readObject(hash)
 	if err != nil {
-		return "", fmt.Errorf("failed to read the contents: %w", err)
+		return nil, err
+	}
+	if typ != "blob" {
+		return nil, fmt.Errorf("%w: expected %s to be a blob, got: %s", ErrUnexpectedObjectType, hash, typ)
 	}
 
-	contents := string(blob)
-	return strings.Split(contents, "\x00")[1], nil
+	return body, nil
 }
 
 func (r *Repository) WriteBlob(fs fs.FS, filename string) (string, error) {
-	hash, blob, err := r.hashBlob(fs, filename)
+	return r.WriteObjectFile(fs, filename, "blob")
+}
+
+func (r *Repository) WriteObjectFile(fs fs.FS, filename string, objType string) (string, error) {
+	hash, object, err := r.hashFile(fs, filename, objType)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash the file: %w", err)
 	}
 
-	dirPath := path.Join(r.root, ".git/objects", hash[:2])
-	err = os.MkdirAll(dirPath, 0755)
+	err = r.storeObject(hash, object)
 	if err != nil {
-		return "", fmt.Errorf("failed to create the directory: %w", err)
+		return "", err
 	}
 
-	blobFile, err := os.Create(path.Join(dirPath, hash[2:]))
+	return hash, nil
+}
+
+func (r *Repository) ReadTree(hash string) (string, error) {
+	entries, err := r.readTreeEntries(hash)
 	if err != nil {
-		return "", fmt.Errorf("failed to create the file: %w", err)
+		return "", err
 	}
 
-	w := zlib.NewWriter(blobFile)
-	/*
-		Remember to close BEFORE you read the contents of the file
-	*/
-	defer w.Close()
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+
+	contents := strings.Join(sort.StringSlice(names), "\n") + "\n"
+	return contents, nil
+}
 
-	_, err = w.Write(blob)
+// ListTree renders a tree the way `git ls-tree` does by default, one
+// "<mode> <type> <hash>\t<name>" line per entry. Types come from the modes, so
+// gitlinks are listed as commits without looking up the submodule's hash.
+func (r *Repository) ListTree(hash string) (string, error) {
+	entries, err := r.readTreeEntries(hash)
 	if err != nil {
-		return "", fmt.Errorf("failed to compress the contents: %w", err)
+		return "", err
 	}
 
-	return hash, nil
+	var sb strings.Builder
+	for _, entry := range entries {
+		sb.WriteString(entry.String() + "\n")
+	}
+
+	return sb.String(), nil
 }
 
-func (r *Repository) ReadTree(hash string) (string, error) {
-	isValid := len([]byte(hash)) == 40
-	if !isValid {
-		return "", fmt.Errorf("%w expected 40 characters, got: %d", ErrInvalidHash, len(hash))
-	}
+// String formats the entry like a line of git ls-tree, without the terminator.
+func (e TreeEntry) String() string {
+	return fmt.Sprintf("%06s %s %s\t%s", e.Mode, e.Type(), e.Hash, e.Name)
+}
 
-	blobPath := path.Join(r.root, ".git", "objects", hash[:2], hash[2:])
-	blobFile, err := os.Open(blobPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+// Type is the type of object the entry points at, derived from its mode.
+func (e TreeEntry) Type() string {
+	switch e.Mode {
+	case "40000":
+		return "tree"
+	case "160000":
+		return "commit"
+	default:
+		return "blob"
 	}
-	defer blobFile.Close()
+}
 
-	reader, err := zlib.NewReader(blobFile)
-	if err != nil {
-		if err != nil {
-			return "", fmt.Errorf("failed to read the contents: %w", err)
+// WriteTree writes dirname as a tree, skipping .git and any directory whose
+// name matches one of the excludes patterns.
+func (r *Repository) WriteTree(dirname string, excludes ...string) (string, error) {
+	/*
+		Identical subtrees and files hash to the same object, so each is stored once per call.
+	*/
+	written := map[string]bool{}
+	hash, err := r.writeTree(dirname, excludes, nil, written)
+	if err != nil && r.RollbackOnError {
+		rollbackErr := r.deleteCreatedObjects(written)
+		if rollbackErr != nil {
+			return "", fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
 		}
 	}
-	defer reader.Close()
 
-	br := bufio.NewReader(reader)
-	typ, err := br.ReadString(' ')
+	return hash, err
+}
+
+func (r *Repository) writeTree(dirname string, excludes []string, ignore *ignoreMatcher, written map[string]bool) (string, error) {
+	treeTable, err := r.treeTable(dirname, excludes, ignore, written)
 	if err != nil {
-		return "", fmt.Errorf("error reading type: %w", err)
-	}
-	if typ != "tree " {
-		return "", fmt.Errorf("expected type to be tree, got: %s", typ)
+		return "", fmt.Errorf("failed to hash the tree: %w", err)
 	}
 
-	_, err = br.ReadString('\x00')
+	hash, err := r.writeObjectOnce("tree", []byte(treeTable), written)
 	if err != nil {
-		return "", fmt.Errorf("error reading null byte: %w", err)
+		return "", fmt.Errorf("failed to write the tree: %w", err)
 	}
 
-	var names []string
-	for {
-		_, err = br.Peek(1)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
+	return hash, nil
+}
 
-			return "", fmt.Errorf("error peeking: %w", err)
-		}
+// HashWorkTree returns the hash git write-tree would give dir after git add -A.
+// Unlike WriteTree, which takes every file, it leaves out what the ignore rules
+// exclude. Nothing is written and dir does not have to be a repository.
+func (r *Repository) HashWorkTree(dir string) (string, error) {
+	dir = absPath(dir)
+	scratch := Repository{root: dir, storage: discardStorage{}, RawMode: true}
+	ignore := newIgnoreMatcher(dir, scratch.infoExcludePath())
 
-		_, err = br.ReadString(' ')
-		if err != nil {
-			return "", fmt.Errorf("error reading mode: %w", err)
+	return scratch.writeTree(dir, nil, ignore, map[string]bool{})
+}
+
+// deleteCreatedObjects removes the objects written records as created by the
+// current write.
+func (r *Repository) deleteCreatedObjects(written map[string]bool) error {
+	for hash, created := range written {
+		if !created {
+			continue
 		}
 
-		name, err := br.ReadString('\x00')
+		err := r.storage.DeleteObject(hash)
 		if err != nil {
-			return "", fmt.Errorf("error reading name: %w", err)
+			return err
 		}
-		names = append(names, strings.Split(name, "\x00")[0])
+	}
 
-		_, err = br.Read(make([]byte, 20))
-		if err != nil {
-			return "", fmt.Errorf("error reading hash: %w", err)
+	return nil
+}
+
+func (r *Repository) VerifyTree(hash string) error {
+	entries, err := r.readTreeEntries(hash)
+	if err != nil {
+		return fmt.Errorf("failed to read tree %s: %w", hash, err)
+	}
+
+	for _, entry := range entries {
+		switch entry.Mode {
+		case "40000":
+			err = r.VerifyTree(entry.Hash)
+			if err != nil {
+				return err
+			}
+
+		case "160000":
+			continue
+
+		default:
+			typ, _, err := r.readObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to read blob %s (%s): %w", entry.Hash, entry.Name, err)
+			}
+			if typ != "blob" {
+				return fmt.Errorf("expected %s (%s) to be a blob, got: %s", entry.Hash, entry.Name, typ)
+			}
 		}
 	}
 
-	contents := strings.Join(sort.StringSlice(names), "\n") + "\n"
-	return contents, nil
+	return nil
 }
 
-func (r *Repository) WriteTree(dirname string) (string, error) {
-	treeTable, err := r.treeTable(dirname)
+func (r *Repository) WriteObject(objType string, body []byte) (string, error) {
+	hash, object, err := hashObject(objType, body)
 	if err != nil {
-		return "", fmt.Errorf("failed to hash the tree: %w", err)
+		return "", err
 	}
 
-	output := fmt.Appendf([]byte{}, "tree %d\x00%s", len(treeTable), treeTable)
+	err = r.storeObject(hash, object)
 	if err != nil {
-		return "", fmt.Errorf("failed to copy the contents: %w", err)
+		return "", err
 	}
 
-	hash := fmt.Sprintf("%x", sha1.Sum(output))
+	return hash, nil
+}
 
-	dirPath := path.Join(r.root, ".git/objects", hash[:2])
-	err = os.MkdirAll(dirPath, 0755)
-	if err != nil {
-		return "", fmt.Errorf("failed to create the directory: %w", err)
+// ImportObject writes an object received from elsewhere, such as a pack. The
+// payload is the object body without its header. When expectedHash is set the
+// object is only written if it hashes to it.
+func (r *Repository) ImportObject(objType string, payload []byte, expectedHash string) (string, error) {
+	if expectedHash != "" {
+		err := ValidateHash(expectedHash)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	treeFile, err := os.Create(path.Join(dirPath, hash[2:]))
+	hash, object, err := hashObject(objType, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to create the file: %w", err)
+		return "", err
 	}
 
-	w := zlib.NewWriter(treeFile)
-	defer w.Close()
+	if expectedHash != "" && hash != expectedHash {
+		return "", fmt.Errorf("%w: expected %s, got %s", ErrHashMismatch, expectedHash, hash)
+	}
 
-	_, err = w.Write(output)
+	err = r.storeObject(hash, object)
 	if err != nil {
-		return "", fmt.Errorf("failed to compress the contents: %w", err)
+		return "", err
 	}
 
 	return hash, nil
 }
 
-func (r *Repository) hashBlob(fsys fs.FS, filename string) (string, []byte, error) {
-	file, err := fsys.Open(filename)
+func (r *Repository) storeObject(hash string, object []byte) error {
+	if r.RawMode {
+		return r.storage.WriteObject(hash, object)
+	}
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, err := w.Write(object)
 	if err != nil {
-		return "", []byte{}, fmt.Errorf("failed to open file: %w", err)
+		return fmt.Errorf("failed to compress the contents: %w", err)
+	}
+
+	/*
+		Remember to close BEFORE you read the contents of the buffer
+	*/
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("failed to flush the contents: %w", err)
+	}
+
+	return r.storage.WriteObject(hash, buf.Bytes())
+}
+
+// ObjectFormat names the hash function a repository uses for object names.
+// Only SHA-1 repositories can be read and written so far.
+type ObjectFormat string
+
+const (
+	ObjectFormatSHA1   ObjectFormat = "sha1"
+	ObjectFormatSHA256 ObjectFormat = "sha256"
+)
+
+func EmptyTreeHash(format ObjectFormat) string {
+	return emptyObjectHash(format, "tree")
+}
+
+func EmptyBlobHash(format ObjectFormat) string {
+	return emptyObjectHash(format, "blob")
+}
+
+func emptyObjectHash(format ObjectFormat, objType string) string {
+	header := []byte(objType + " 0\x00")
+	if format == ObjectFormatSHA256 {
+		return fmt.Sprintf("%x", sha256.Sum256(header))
 	}
-	defer file.Close()
 
-	info, err := file.Stat()
+	return fmt.Sprintf("%x", sha1.Sum(header))
+}
+
+func (r *Repository) hashFile(fsys fs.FS, filename string, objType string) (string, []byte, error) {
+	file, err := fsys.Open(filename)
 	if err != nil {
-		return "", []byte{}, fmt.Errorf("failed to get file info: %w", err)
+		return "", []byte{}, fmt.Errorf("failed to open file: %w", err)
 	}
+	defer file.Close()
 
 	fBuf, err := ioutil.ReadAll(file)
 	if err != nil {
 		return "", []byte{}, fmt.Errorf("failed to copy the contents: %w", err)
 	}
 
-	header := append([]byte(fmt.Sprintf("blob %d", info.Size())), byte(0))
-	blob := append(header, fBuf...)
+	return hashObject(objType, fBuf)
+}
+
+// HashObject computes the hash git would give an object without storing it,
+// so it needs no repository.
+func HashObject(objType string, body []byte) (string, error) {
+	hash, _, err := hashObject(objType, body)
+	return hash, err
+}
+
+func hashObject(objType string, body []byte) (string, []byte, error) {
+	switch objType {
+	case "blob", "tree", "commit", "tag":
+	default:
+		return "", nil, fmt.Errorf("%w: %s", ErrInvalidObjectType, objType)
+	}
+
+	header := append([]byte(fmt.Sprintf("%s %d", objType, len(body))), byte(0))
+	object := append(header, body...)
 
-	hash := fmt.Sprintf("%x", sha1.Sum(blob))
-	return hash, blob, nil
+	hash := fmt.Sprintf("%x", sha1.Sum(object))
+	return hash, object, nil
 }
 
-func (r *Repository) treeTable(dirname string) (string, error) {
+func (r *Repository) treeTable(dirname string, excludes []string, ignore *ignoreMatcher, written map[string]bool) (string, error) {
 	dirEntries, err := os.ReadDir(dirname)
 	if err != nil {
 		return "", fmt.Errorf("failed to read the directory: %w", err)
 	}
 
+	/*
+		Git sorts tree entries as if directory names had a trailing slash.
+	*/
+	sortKey := func(dirEntry fs.DirEntry) string {
+		if dirEntry.IsDir() {
+			return dirEntry.Name() + "/"
+		}
+
+		return dirEntry.Name()
+	}
+	sort.Slice(dirEntries, func(i, j int) bool {
+		return sortKey(dirEntries[i]) < sortKey(dirEntries[j])
+	})
+
 	var table []byte
 	for _, dirEntry := range dirEntries {
-		if dirEntry.IsDir() {
-			if dirEntry.Name() == ".git" {
+		var mode, hash string
+
+		if ignore != nil {
+			ignored, err := ignore.ignored(filepath.Join(dirname, dirEntry.Name()), dirEntry.IsDir())
+			if err != nil {
+				return "", err
+			}
+			if ignored {
+				continue
+			}
+		}
+
+		switch {
+		case dirEntry.IsDir():
+			if dirEntry.Name() == ".git" || isExcluded(dirEntry.Name(), excludes) {
+				continue
+			}
+
+			subTable, err := r.treeTable(filepath.Join(dirname, dirEntry.Name()), excludes, ignore, written)
+			if err != nil {
+				return "", fmt.Errorf("failed to write the tree: %w", err)
+			}
+
+			/*
+				Git does not track empty directories.
+			*/
+			if subTable == "" {
 				continue
 			}
 
-			subHash, err := r.treeTable((path.Join(dirname, dirEntry.Name())))
+			mode = "40000"
+			hash, err = r.writeObjectOnce("tree", []byte(subTable), written)
 			if err != nil {
 				return "", fmt.Errorf("failed to write the tree: %w", err)
 			}
 
-			table = fmt.Appendf(table, "40000 %s\x00%x", dirEntry.Name(), sha1.Sum([]byte(subHash)))
+		case dirEntry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(filepath.Join(dirname, dirEntry.Name()))
+			if err != nil {
+				return "", fmt.Errorf("failed to read the symlink: %w", err)
+			}
 
-		} else {
-			hash, _, err := r.hashBlob(os.DirFS(dirname), dirEntry.Name())
+			mode = "120000"
+			hash, err = r.writeObjectOnce("blob", []byte(target), written)
+			if err != nil {
+				return "", fmt.Errorf("failed to write the symlink: %w", err)
+			}
+
+		default:
+			info, err := dirEntry.Info()
+			if err != nil {
+				return "", fmt.Errorf("failed to get file info: %w", err)
+			}
+
+			mode = "100644"
+			if info.Mode()&0111 != 0 {
+				mode = "100755"
+			}
+
+			var object []byte
+			hash, object, err = r.hashFile(os.DirFS(dirname), dirEntry.Name(), "blob")
 			if err != nil {
 				return "", fmt.Errorf("failed to hash the file: %w", err)
 			}
 
-			table = fmt.Appendf(table, "100644 %s\x00%s", dirEntry.Name(), hash)
+			err = r.storeObjectOnce(hash, object, written)
+			if err != nil {
+				return "", fmt.Errorf("failed to write the file: %w", err)
+			}
+		}
+
+		rawHash, err := hex.DecodeString(hash)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode hash %s: %w", hash, err)
 		}
+
+		table = fmt.Appendf(table, "%s %s\x00%s", mode, dirEntry.Name(), rawHash)
 	}
 
 	return string(table), nil
 }
+
+func (r *Repository) writeObjectOnce(objType string, body []byte, written map[string]bool) (string, error) {
+	hash, object, err := hashObject(objType, body)
+	if err != nil {
+		return "", err
+	}
+
+	err = r.storeObjectOnce(hash, object, written)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// storeObjectOnce skips objects already stored during the current write,
+// recording the rest in written along with whether this write created them.
+func (r *Repository) storeObjectOnce(hash string, object []byte, written map[string]bool) error {
+	if _, ok := written[hash]; ok {
+		return nil
+	}
+
+	existed := r.storage.HasObject(hash)
+	err := r.storeObject(hash, object)
+	if err != nil {
+		return err
+	}
+	written[hash] = !existed
+
+	return nil
+}
+
+func isExcluded(name string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if matched, _ := path.Match(exclude, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Repository) OpenObject(hash string) (string, int64, io.ReadCloser, error) {
+	err := ValidateHash(hash)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	objectFile, err := r.storage.ReadObject(hash)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	var reader io.ReadCloser
+	fileReader := bufio.NewReader(objectFile)
+	pooled := isZlib(fileReader)
+	if pooled {
+		reader, err = getZlibReader(fileReader)
+		if err != nil {
+			objectFile.Close()
+			return "", 0, nil, fmt.Errorf("failed to read the contents: %w", err)
+		}
+	} else {
+		reader = io.NopCloser(fileReader)
+	}
+
+	br := bufio.NewReader(reader)
+	rc := &objectReader{r: br, zlib: reader, pooled: pooled, file: objectFile}
+	typ, size, err := readObjectHeader(br)
+	if err != nil {
+		rc.Close()
+		return "", 0, nil, fmt.Errorf("failed to read the header of %s: %w", hash, err)
+	}
+
+	return typ, size, rc, nil
+}
+
+// ObjectReader streams the body of an object, after its header, exactly as
+// stored. Whatever the type, nothing is parsed or reformatted.
+func (r *Repository) ObjectReader(hash string) (io.ReadCloser, error) {
+	_, _, rc, err := r.OpenObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return rc, nil
+}
+
+// ReadObjectHeader returns the type and size of an object, inflating only as
+// much of it as is needed to reach the end of the header.
+func (r *Repository) ReadObjectHeader(hash string) (string, int64, error) {
+	typ, size, rc, err := r.OpenObject(hash)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = rc.Close()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to close %s: %w", hash, err)
+	}
+
+	return typ, size, nil
+}
+
+// ObjectExists reports whether hash names a stored object whose header parses
+// and declares a known type, without reading the rest of it.
+func (r *Repository) ObjectExists(hash string) bool {
+	if ValidateHash(hash) != nil || !r.hasObject(hash) {
+		return false
+	}
+
+	typ, _, err := r.ReadObjectHeader(hash)
+	if err != nil {
+		return false
+	}
+
+	_, _, err = hashObject(typ, nil)
+	return err == nil
+}
+
+func (r *Repository) readObject(hash string) (string, []byte, error) {
+	typ, size, rc, err := r.OpenObject(hash)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rc.Close()
+
+	maxSize := r.MaxObjectSize
+	if maxSize == 0 {
+		maxSize = defaultMaxObjectSize
+	}
+	if size > maxSize {
+		return "", nil, fmt.Errorf("%w: %s declares %d bytes, the limit is %d", ErrObjectTooLarge, hash, size, maxSize)
+	}
+
+	body := make([]byte, size)
+	_, err = io.ReadFull(rc, body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read the contents: %w", err)
+	}
+
+	return typ, body, nil
+}
+
+func (r *Repository) readTreeEntries(hash string) ([]TreeEntry, error) {
+	typ, body, err := r.readObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "tree" {
+		return nil, fmt.Errorf("expected type to be tree, got: %s", typ)
+	}
+
+	var entries []TreeEntry
+	err = parseTreeEntries(bufio.NewReader(bytes.NewReader(body)), func(entry TreeEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// EachTreeEntry calls fn with each entry of a tree as it is parsed, without
+// holding the whole tree in memory. It stops at the first error fn returns.
+func (r *Repository) EachTreeEntry(hash string, fn func(TreeEntry) error) error {
+	typ, _, rc, err := r.OpenObject(hash)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if typ != "tree" {
+		return fmt.Errorf("expected type to be tree, got: %s", typ)
+	}
+
+	return parseTreeEntries(bufio.NewReader(rc), fn)
+}
+
+func parseTreeEntries(br *bufio.Reader, fn func(TreeEntry) error) error {
+	for {
+		mode, err := br.ReadString(' ')
+		if err == io.EOF && mode == "" {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading mode: %w", unexpectedEOF(err))
+		}
+
+		name, err := br.ReadString(0)
+		if err != nil {
+			return fmt.Errorf("error reading name: %w", unexpectedEOF(err))
+		}
+
+		rawHash := make([]byte, 20)
+		_, err = io.ReadFull(br, rawHash)
+		if err != nil {
+			return fmt.Errorf("error reading hash: %w", unexpectedEOF(err))
+		}
+
+		err = fn(TreeEntry{
+			Mode: strings.TrimSuffix(mode, " "),
+			Name: strings.TrimSuffix(name, "\x00"),
+			Hash: hex.EncodeToString(rawHash),
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unexpectedEOF reports running out of input partway through an entry as
+// io.ErrUnexpectedEOF rather than io.EOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// zlibReaders keeps inflaters between objects, reset onto each new stream
+// instead of being allocated afresh for every read.
+var zlibReaders sync.Pool
+
+func getZlibReader(src io.Reader) (io.ReadCloser, error) {
+	zr, ok := zlibReaders.Get().(io.ReadCloser)
+	if !ok {
+		return zlib.NewReader(src)
+	}
+
+	err := zr.(zlib.Resetter).Reset(src, nil)
+	if err != nil {
+		zlibReaders.Put(zr)
+		return nil, err
+	}
+
+	return zr, nil
+}
+
+type objectReader struct {
+	r      io.Reader
+	zlib   io.ReadCloser
+	pooled bool
+	file   io.Closer
+	closed bool
+}
+
+func (o *objectReader) Read(p []byte) (int, error) {
+	/*
+		Once closed, the inflater may already be reading another object.
+	*/
+	if o.closed {
+		return 0, fs.ErrClosed
+	}
+
+	return o.r.Read(p)
+}
+
+func (o *objectReader) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+
+	zlibErr := o.zlib.Close()
+	if o.pooled {
+		zlibReaders.Put(o.zlib)
+	}
+	fileErr := o.file.Close()
+	if zlibErr != nil {
+		return zlibErr
+	}
+
+	return fileErr
+}
+
+// isZlib checks for the zlib header: the deflate method with a checksum making
+// the first two bytes a multiple of 31. Raw objects start with their type name.
+func isZlib(br *bufio.Reader) bool {
+	magic, err := br.Peek(2)
+	if err != nil {
+		return false
+	}
+
+	return magic[0]&0x0f == 8 && (uint16(magic[0])<<8|uint16(magic[1]))%31 == 0
+}
+
+func readObjectHeader(br *bufio.Reader) (string, int64, error) {
+	typ, err := br.ReadString(' ')
+	if err != nil {
+		return "", 0, fmt.Errorf("error reading type: %w", err)
+	}
+
+	size, err := br.ReadString('\x00')
+	if err != nil {
+		return "", 0, fmt.Errorf("error reading size: %w", err)
+	}
+
+	parsedSize, err := strconv.ParseInt(strings.TrimSuffix(size, "\x00"), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("error parsing size: %w", err)
+	}
+	if parsedSize < 0 {
+		return "", 0, fmt.Errorf("error parsing size: negative size %d", parsedSize)
+	}
+
+	return strings.TrimSuffix(typ, " "), parsedSize, nil
+}
+
+func (r *Repository) ListObjects() ([]string, error) {
+	return r.storage.ListObjects()
+}
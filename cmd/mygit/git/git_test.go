@@ -1,12 +1,19 @@
 package git_test
 
 import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -15,7 +22,7 @@ import (
 
 func TestInitialize(t *testing.T) {
 	t.Run("Initializes the repository with the right files", func(t *testing.T) {
-		root := os.TempDir()
+		root := t.TempDir()
 
 		repository := git.NewRepository(root)
 		cleanup, err := repository.Init()
@@ -47,7 +54,7 @@ func TestInitialize(t *testing.T) {
 	})
 
 	t.Run("Cannot initialize the repository twice", func(t *testing.T) {
-		root := os.TempDir()
+		root := t.TempDir()
 		repository := git.NewRepository(root)
 
 		cleanup, err := repository.Init()
@@ -65,11 +72,228 @@ func TestInitialize(t *testing.T) {
 			t.Fatalf("expected error %v, got %v", git.ErrRepositoryAlreadyInitialized, err)
 		}
 	})
+
+	t.Run("Resolves a relative root against the working directory", func(t *testing.T) {
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("error getting working directory: %v", err)
+		}
+		defer os.Chdir(wd)
+
+		parent := t.TempDir()
+		err = os.Chdir(parent)
+		if err != nil {
+			t.Fatalf("error changing directory: %v", err)
+		}
+
+		repository := git.NewRepository("relative")
+		_, err = repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		err = os.Chdir(t.TempDir())
+		if err != nil {
+			t.Fatalf("error changing directory: %v", err)
+		}
+
+		hash, err := repository.WriteObject("blob", []byte("relative"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		_, err = os.Stat(filepath.Join(parent, "relative", ".git", "objects", hash[:2], hash[2:]))
+		if err != nil {
+			t.Fatalf("expected the object under the original directory: %v", err)
+		}
+	})
+
+	t.Run("Detects a repository initialized on disk", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			setup   []string
+			newRepo func(root string) git.Repository
+		}{
+			{name: "non-bare", setup: []string{"init", "-q"}, newRepo: git.NewRepository},
+			{name: "bare", setup: []string{"init", "-q", "--bare"}, newRepo: git.NewBareRepository},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				root := t.TempDir()
+				runGit(t, root, tt.setup...)
+
+				repository := tt.newRepo(root)
+				cleanup, err := repository.Init()
+				if !errors.Is(err, git.ErrRepositoryAlreadyInitialized) {
+					t.Fatalf("expected error %v, got %v", git.ErrRepositoryAlreadyInitialized, err)
+				}
+
+				err = cleanup()
+				if err != nil {
+					t.Fatalf("error running cleanup: %v", err)
+				}
+
+				runGit(t, root, "rev-parse", "--git-dir")
+			})
+		}
+	})
+
+	t.Run("Refuses a bare target that already holds objects or HEAD", func(t *testing.T) {
+		for _, name := range []string{"objects", "HEAD"} {
+			root := t.TempDir()
+			err := os.WriteFile(filepath.Join(root, "README"), []byte("unrelated\n"), 0644)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+
+			repository := git.NewBareRepository(root)
+			_, err = repository.Init()
+			if err != nil {
+				t.Fatalf("expected unrelated files not to block init, got %v", err)
+			}
+
+			root = t.TempDir()
+			err = os.WriteFile(filepath.Join(root, name), nil, 0644)
+			if err != nil {
+				t.Fatalf("error writing %s: %v", name, err)
+			}
+
+			repository = git.NewBareRepository(root)
+			_, err = repository.Init()
+			if !errors.Is(err, git.ErrRepositoryAlreadyInitialized) {
+				t.Fatalf("expected error %v with %s present, got %v", git.ErrRepositoryAlreadyInitialized, name, err)
+			}
+		}
+	})
+
+	t.Run("Keeps an existing repository intact across a second init", func(t *testing.T) {
+		root := t.TempDir()
+		first := git.NewRepository(root)
+		_, err := first.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		runGit(t, root, "symbolic-ref", "HEAD", "refs/heads/main")
+		hash, err := first.WriteObject("blob", []byte("kept"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		second := git.NewRepository(root)
+		cleanup, err := second.Init()
+		if !errors.Is(err, git.ErrRepositoryAlreadyInitialized) {
+			t.Fatalf("expected error %v, got %v", git.ErrRepositoryAlreadyInitialized, err)
+		}
+
+		err = cleanup()
+		if err != nil {
+			t.Fatalf("error running cleanup: %v", err)
+		}
+
+		err = os.RemoveAll(filepath.Join(root, ".git", "refs"))
+		if err != nil {
+			t.Fatalf("error removing refs: %v", err)
+		}
+
+		err = second.Reinit()
+		if err != nil {
+			t.Fatalf("error reinitializing repository: %v", err)
+		}
+
+		if head := runGit(t, root, "symbolic-ref", "HEAD"); head != "refs/heads/main" {
+			t.Fatalf("expected HEAD to survive, got %s", head)
+		}
+
+		if !second.ObjectExists(hash) {
+			t.Fatalf("expected object %s to survive", hash)
+		}
+
+		_, err = os.Stat(filepath.Join(root, ".git", "refs"))
+		if err != nil {
+			t.Fatalf("expected refs to be recreated: %v", err)
+		}
+	})
+}
+
+func TestInitDefaultBranch(t *testing.T) {
+	globalConfig := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
+
+	readHead := func(t *testing.T, root string) string {
+		t.Helper()
+		contents, err := os.ReadFile(filepath.Join(root, ".git", "HEAD"))
+		if err != nil {
+			t.Fatalf("error reading HEAD: %v", err)
+		}
+		return string(contents)
+	}
+
+	t.Run("Defaults to master", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		if head := readHead(t, root); head != "ref: refs/heads/master\n" {
+			t.Fatalf("expected HEAD on master, got %q", head)
+		}
+	})
+
+	err := os.WriteFile(globalConfig, []byte("[init]\n\tdefaultBranch = trunk\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing global config: %v", err)
+	}
+
+	t.Run("Reads init.defaultBranch from the global config", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		if head := readHead(t, root); head != "ref: refs/heads/trunk\n" {
+			t.Fatalf("expected HEAD on trunk, got %q", head)
+		}
+	})
+
+	t.Run("Prefers an explicit branch over the config", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		repository.DefaultBranch = "main"
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		if head := readHead(t, root); head != "ref: refs/heads/main\n" {
+			t.Fatalf("expected HEAD on main, got %q", head)
+		}
+	})
+
+	t.Run("Refuses an invalid branch name", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		repository.DefaultBranch = "bad..name"
+		_, err := repository.Init()
+		if !errors.Is(err, git.ErrInvalidBranchName) {
+			t.Fatalf("expected error %v, got %v", git.ErrInvalidBranchName, err)
+		}
+
+		_, err = os.Stat(filepath.Join(root, ".git"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected nothing to be created, got %v", err)
+		}
+	})
 }
 
 func TestCatFile(t *testing.T) {
 	t.Run("Fails to read the blob if the SHA is invalid", func(t *testing.T) {
-		root := os.TempDir()
+		root := t.TempDir()
 		repository := git.NewRepository(root)
 
 		_, err := repository.CatFile("123")
@@ -83,7 +307,7 @@ func TestCatFile(t *testing.T) {
 	})
 
 	t.Run("Reads the blob", func(t *testing.T) {
-		root := os.TempDir()
+		root := t.TempDir()
 
 		repository := git.NewRepository(root)
 		cleanup, err := repository.Init()
@@ -113,11 +337,170 @@ func TestCatFile(t *testing.T) {
 			t.Fatalf("expected test content, got %s", contents)
 		}
 	})
+
+	t.Run("Pretty-prints trees in ls-tree format", func(t *testing.T) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q")
+		for _, name := range []string{"a.txt", "b.txt"} {
+			err := os.WriteFile(path.Join(root, name), []byte(name), 0644)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+		}
+		runGit(t, root, "add", ".")
+		treeHash := runGit(t, root, "write-tree")
+
+		expected := runGit(t, root, "cat-file", "-p", treeHash) + "\n"
+
+		repository := git.NewRepository(root)
+		contents, err := repository.CatFile(treeHash)
+		if err != nil {
+			t.Fatalf("error reading tree: %v", err)
+		}
+
+		if contents != expected {
+			t.Fatalf("expected %q, got %q", expected, contents)
+		}
+
+		if strings.ContainsRune(contents, 0) {
+			t.Fatalf("expected readable output, got raw tree bytes %q", contents)
+		}
+	})
+
+	t.Run("Resolves refs and tree paths", func(t *testing.T) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q", "-b", "main")
+
+		err := os.MkdirAll(path.Join(root, "src"), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+		files := map[string]string{"file.txt": "top level\n", "src/main.go": "package main\n"}
+		for name, contents := range files {
+			err = os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+		}
+		runGit(t, root, "add", ".")
+		runGit(t, root, "commit", "-q", "-m", "initial")
+
+		repository := git.NewRepository(root)
+		expectedCommit := runGit(t, root, "cat-file", "-p", "HEAD") + "\n"
+
+		tests := map[string]string{
+			"HEAD":             expectedCommit,
+			"main":             expectedCommit,
+			"HEAD:file.txt":    "top level\n",
+			"main:src/main.go": "package main\n",
+		}
+		for rev, expected := range tests {
+			contents, err := repository.CatFile(rev)
+			if err != nil {
+				t.Fatalf("error reading %s: %v", rev, err)
+			}
+
+			if contents != expected {
+				t.Fatalf("expected %s to be %q, got %q", rev, expected, contents)
+			}
+		}
+
+		_, err = repository.CatFile("HEAD:missing.txt")
+		if err == nil {
+			t.Fatalf("expected error reading a missing path, got nil")
+		}
+	})
+
+	t.Run("Refuses objects declaring more than the size limit", func(t *testing.T) {
+		storage := git.NewMemoryStorage()
+		repository := git.NewRepositoryWithStorage(t.TempDir(), storage)
+
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		_, err := w.Write([]byte("blob 999999999999999\x00tiny"))
+		if err != nil {
+			t.Fatalf("error compressing object: %v", err)
+		}
+		w.Close()
+
+		const hash = "1111111111111111111111111111111111111111"
+		err = storage.WriteObject(hash, buf.Bytes())
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		_, err = repository.CatFile(hash)
+		if !errors.Is(err, git.ErrObjectTooLarge) {
+			t.Fatalf("expected error %v, got %v", git.ErrObjectTooLarge, err)
+		}
+
+		small, err := repository.WriteObject("blob", []byte("just over four bytes"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		repository.MaxObjectSize = 4
+		_, err = repository.CatFile(small)
+		if !errors.Is(err, git.ErrObjectTooLarge) {
+			t.Fatalf("expected error %v with a lowered limit, got %v", git.ErrObjectTooLarge, err)
+		}
+
+		buf.Reset()
+		w = zlib.NewWriter(&buf)
+		_, err = w.Write([]byte("blob -1\x00tiny"))
+		if err != nil {
+			t.Fatalf("error compressing object: %v", err)
+		}
+		w.Close()
+
+		const negative = "2222222222222222222222222222222222222222"
+		err = storage.WriteObject(negative, buf.Bytes())
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		_, err = repository.CatFile(negative)
+		if err == nil {
+			t.Fatalf("expected error reading an object with a negative size, got nil")
+		}
+	})
+}
+
+func TestCatBlob(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	err := os.WriteFile(path.Join(root, "file.txt"), []byte("blob content\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	runGit(t, root, "add", "file.txt")
+	tree := runGit(t, root, "write-tree")
+	blob := runGit(t, root, "rev-parse", tree+":file.txt")
+
+	repository := git.NewRepository(root)
+
+	t.Run("Reads a blob", func(t *testing.T) {
+		contents, err := repository.CatBlob(blob)
+		if err != nil {
+			t.Fatalf("error reading blob: %v", err)
+		}
+
+		if string(contents) != "blob content\n" {
+			t.Fatalf("expected blob content, got %q", contents)
+		}
+	})
+
+	t.Run("Refuses a tree", func(t *testing.T) {
+		_, err := repository.CatBlob(tree)
+		if !errors.Is(err, git.ErrUnexpectedObjectType) {
+			t.Fatalf("expected error %v, got %v", git.ErrUnexpectedObjectType, err)
+		}
+	})
 }
 
 func TestHashFile(t *testing.T) {
 	t.Run("succeeds", func(t *testing.T) {
-		root := os.TempDir()
+		root := t.TempDir()
 		repository := git.NewRepository(root)
 
 		fileContents := "test content"
@@ -165,11 +548,50 @@ func TestHashFile(t *testing.T) {
 			t.Fatalf("expected %s, got %s", fileContents, contents)
 		}
 	})
+
+	t.Run("Hashes a commit body with the commit type", func(t *testing.T) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q")
+		runGit(t, root, "commit", "-q", "--allow-empty", "-m", "initial")
+		expected := runGit(t, root, "rev-parse", "HEAD")
+
+		cmd := exec.Command("git", "cat-file", "commit", expected)
+		cmd.Dir = root
+		body, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("error reading commit: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, "commit.txt"), body, 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+
+		repository := git.NewRepository(root)
+		hash, err := repository.WriteObjectFile(os.DirFS(root), "commit.txt", "commit")
+		if err != nil {
+			t.Fatalf("error hashing file: %v", err)
+		}
+
+		if hash != expected {
+			t.Fatalf("expected %s, got %s", expected, hash)
+		}
+	})
+
+	t.Run("Rejects unknown object types", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+
+		_, err := repository.WriteObject("banana", []byte("body"))
+		if !errors.Is(err, git.ErrInvalidObjectType) {
+			t.Fatalf("expected error %v, got %v", git.ErrInvalidObjectType, err)
+		}
+	})
 }
 
 func TestReadTree(t *testing.T) {
 	t.Run("succeeds", func(t *testing.T) {
-		root := os.TempDir()
+		root := t.TempDir()
 
 		repository := git.NewRepository(root)
 		cleanup, err := repository.Init()
@@ -197,6 +619,46 @@ func TestReadTree(t *testing.T) {
 
 		fmt.Println(output)
 	})
+
+	t.Run("Lists gitlinks as commits without reading them", func(t *testing.T) {
+		root := t.TempDir()
+		runGit(t, root, "init", "-q")
+
+		err := os.WriteFile(path.Join(root, "file.txt"), []byte("file"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+		runGit(t, root, "add", "file.txt")
+
+		/*
+			The submodule commit does not exist in this repository, so reading it would fail.
+		*/
+		const submodule = "1234567890123456789012345678901234567890"
+		runGit(t, root, "update-index", "--add", "--cacheinfo", "160000,"+submodule+",vendor/lib")
+		tree := runGit(t, root, "write-tree")
+		vendor := runGit(t, root, "rev-parse", tree+":vendor")
+
+		repository := git.NewRepository(root)
+		listing, err := repository.ListTree(vendor)
+		if err != nil {
+			t.Fatalf("error listing tree: %v", err)
+		}
+
+		expected := runGit(t, root, "ls-tree", vendor) + "\n"
+		if listing != expected {
+			t.Fatalf("expected %q, got %q", expected, listing)
+		}
+
+		listing, err = repository.ListTree(tree)
+		if err != nil {
+			t.Fatalf("error listing tree: %v", err)
+		}
+
+		expected = runGit(t, root, "ls-tree", tree) + "\n"
+		if listing != expected {
+			t.Fatalf("expected %q, got %q", expected, listing)
+		}
+	})
 }
 
 func TestWriteTree(t *testing.T) {
@@ -207,7 +669,7 @@ func TestWriteTree(t *testing.T) {
 			t.Fatalf("error getting working directory: %v", err)
 		}
 
-		root := os.TempDir()
+		root := t.TempDir()
 		cmd := exec.Command("cp", "-r", path.Join(wd, "./fixtures/writing_tree"), root)
 		err = cmd.Run()
 		if err != nil {
@@ -229,6 +691,163 @@ func TestWriteTree(t *testing.T) {
 
 		fmt.Println(tree)
 	})
+
+	t.Run("Skips excluded directories", func(t *testing.T) {
+		root := t.TempDir()
+		files := []string{"main.go", "node_modules/dep/index.js", "vendor/lib.go", "src/vendor.go"}
+		for _, name := range files {
+			p := path.Join(root, name)
+			err := os.MkdirAll(path.Dir(p), 0755)
+			if err != nil {
+				t.Fatalf("error creating directory: %v", err)
+			}
+
+			err = os.WriteFile(p, []byte(name), 0644)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+		}
+
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		hash, err := repository.WriteTree(root, "node_modules", "vend*")
+		if err != nil {
+			t.Fatalf("error writing tree: %v", err)
+		}
+
+		tree, err := repository.ReadTree(hash)
+		if err != nil {
+			t.Fatalf("error reading tree: %v", err)
+		}
+
+		if tree != "main.go\nsrc\n" {
+			t.Fatalf("expected only main.go and src, got %q", tree)
+		}
+	})
+
+	t.Run("Writes an empty directory as the empty tree", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		err = os.MkdirAll(path.Join(root, "empty", "nested"), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		hash, err := repository.WriteTree(path.Join(root, "empty"))
+		if err != nil {
+			t.Fatalf("error writing tree: %v", err)
+		}
+
+		expected := git.EmptyTreeHash(git.ObjectFormatSHA1)
+		if hash != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" || hash != expected {
+			t.Fatalf("expected the empty tree %s, got %s", expected, hash)
+		}
+
+		runGit(t, root, "cat-file", "-e", hash)
+	})
+
+	t.Run("Writes identical subtrees once", func(t *testing.T) {
+		root := t.TempDir()
+		for _, name := range []string{"a/x/file.txt", "b/x/file.txt", "c/file.txt"} {
+			p := path.Join(root, name)
+			err := os.MkdirAll(path.Dir(p), 0755)
+			if err != nil {
+				t.Fatalf("error creating directory: %v", err)
+			}
+
+			err = os.WriteFile(p, []byte("same\n"), 0644)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+		}
+
+		storage := &countingStorage{Storage: git.NewMemoryStorage()}
+		repository := git.NewRepositoryWithStorage(root, storage)
+
+		hash, err := repository.WriteTree(root)
+		if err != nil {
+			t.Fatalf("error writing tree: %v", err)
+		}
+
+		for written, count := range storage.writes {
+			if count != 1 {
+				t.Fatalf("expected %s to be written once, got %d writes", written, count)
+			}
+		}
+
+		/*
+			The blob, x/ (= c/), a/ (= b/) and the root.
+		*/
+		if len(storage.writes) != 4 {
+			t.Fatalf("expected 4 distinct objects, got %d", len(storage.writes))
+		}
+
+		runGit(t, root, "init")
+		runGit(t, root, "add", ".")
+		expected := runGit(t, root, "write-tree")
+		if hash != expected {
+			t.Fatalf("expected tree %s, got %s", expected, hash)
+		}
+	})
+
+	t.Run("Rolls back the objects it created when it fails", func(t *testing.T) {
+		root := t.TempDir()
+		for _, name := range []string{"a.txt", "b.txt", "c.txt", "dir/d.txt"} {
+			p := path.Join(root, name)
+			err := os.MkdirAll(path.Dir(p), 0755)
+			if err != nil {
+				t.Fatalf("error creating directory: %v", err)
+			}
+
+			err = os.WriteFile(p, []byte(name+"\n"), 0644)
+			if err != nil {
+				t.Fatalf("error writing file: %v", err)
+			}
+		}
+
+		for _, rollback := range []bool{false, true} {
+			storage := &failingStorage{Storage: git.NewMemoryStorage(), allowed: 3}
+			repository := git.NewRepositoryWithStorage(root, storage)
+			repository.RollbackOnError = rollback
+
+			existing, err := repository.WriteObject("blob", []byte("a.txt\n"))
+			if err != nil {
+				t.Fatalf("error writing object: %v", err)
+			}
+
+			_, err = repository.WriteTree(root)
+			if !errors.Is(err, errInjected) {
+				t.Fatalf("expected the injected error, got %v", err)
+			}
+
+			hashes, err := repository.ListObjects()
+			if err != nil {
+				t.Fatalf("error listing objects: %v", err)
+			}
+
+			/*
+				a.txt was already stored and its rewrite counts against the allowance, so b.txt is the only new object.
+			*/
+			expected := []string{existing}
+			if !rollback {
+				expected = []string{existing, runGit(t, root, "hash-object", "b.txt")}
+				sort.Strings(expected)
+			}
+
+			if !reflect.DeepEqual(hashes, expected) {
+				t.Fatalf("expected %v with rollback %t, got %v", expected, rollback, hashes)
+			}
+		}
+	})
 }
 
 func cleanup(t *testing.T, p string) {
@@ -238,3 +857,237 @@ func cleanup(t *testing.T, p string) {
 		t.Fatalf("error cleaning up: %v", err)
 	}
 }
+
+// TestMain points the global config at an empty file, so settings such as
+// init.defaultBranch on the machine running the tests cannot change results.
+func TestMain(m *testing.M) {
+	globalConfig, err := os.CreateTemp("", "gitconfig")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating global config: %v\n", err)
+		os.Exit(1)
+	}
+	globalConfig.Close()
+	os.Setenv("GIT_CONFIG_GLOBAL", globalConfig.Name())
+
+	code := m.Run()
+	os.Remove(globalConfig.Name())
+	os.Exit(code)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_AUTHOR_DATE=1700000000 +0000",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_COMMITTER_DATE=1700000000 +0000",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("error running git %v: %v: %s", args, err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func TestHashWorkTree(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":      "*.log\nbuild/\n",
+		"main.go":         "package main",
+		"debug.log":       "ignored",
+		"build/out.bin":   "ignored",
+		"src/b/lib.go":    "package b",
+		"src/b.go":        "package src",
+		"src/.gitignore":  "!keep.log\n",
+		"src/keep.log":    "kept",
+		"scripts/run.sh":  "#!/bin/sh",
+		"empty/.gitkeep":  "",
+		"src/b-c/util.go": "package c",
+	}
+	for name, contents := range files {
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	err := os.Chmod(path.Join(root, "scripts/run.sh"), 0755)
+	if err != nil {
+		t.Fatalf("error making the script executable: %v", err)
+	}
+
+	repository := git.NewRepository(root)
+	hash, err := repository.HashWorkTree(root)
+	if err != nil {
+		t.Fatalf("error hashing the working tree: %v", err)
+	}
+
+	_, err = os.Stat(path.Join(root, ".git"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected nothing to be written, got %v", err)
+	}
+
+	runGit(t, root, "init", "-q")
+	runGit(t, root, "add", "-A")
+	expected := runGit(t, root, "write-tree")
+	if hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	/*
+		WriteTree takes ignored files too, so only HashWorkTree matches what git add -A records.
+	*/
+	everything, err := repository.WriteTree(root)
+	if err != nil {
+		t.Fatalf("error writing the tree: %v", err)
+	}
+
+	if everything == hash {
+		t.Fatalf("expected WriteTree to include the ignored files")
+	}
+
+	if runGit(t, root, "cat-file", "-p", everything+":debug.log") != "ignored" {
+		t.Fatalf("expected WriteTree to record debug.log")
+	}
+}
+
+func TestVerifyTree(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	cmd := exec.Command("cp", "-r", path.Join(wd, "./fixtures/writing_tree"), tempDir)
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error copying testdata: %v", err)
+	}
+
+	root := path.Join(tempDir, "writing_tree")
+	repository := git.NewRepository(root)
+	_, err = repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	hash, err := repository.WriteTree(root)
+	if err != nil {
+		t.Fatalf("error writing tree: %v", err)
+	}
+
+	t.Run("Succeeds when every referenced object is present", func(t *testing.T) {
+		runGit(t, root, "add", ".")
+		expected := runGit(t, root, "write-tree")
+		if hash != expected {
+			t.Fatalf("expected tree hash %s, got %s", expected, hash)
+		}
+
+		err := repository.VerifyTree(hash)
+		if err != nil {
+			t.Fatalf("error verifying tree: %v", err)
+		}
+	})
+
+	t.Run("Fails with the hash of a missing blob", func(t *testing.T) {
+		blobHash := runGit(t, root, "hash-object", "directory/nested_directory/d.txt")
+		err := os.Remove(path.Join(root, ".git", "objects", blobHash[:2], blobHash[2:]))
+		if err != nil {
+			t.Fatalf("error removing blob: %v", err)
+		}
+
+		err = repository.VerifyTree(hash)
+		if err == nil {
+			t.Fatalf("expected error verifying tree, got nil")
+		}
+
+		if !strings.Contains(err.Error(), blobHash) {
+			t.Fatalf("expected error to mention %s, got %v", blobHash, err)
+		}
+	})
+}
+
+func writeRawObject(t *testing.T, root string, typ string, body []byte) string {
+	t.Helper()
+	object := append([]byte(fmt.Sprintf("%s %d\x00", typ, len(body))), body...)
+	hash := fmt.Sprintf("%x", sha1.Sum(object))
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, err := w.Write(object)
+	if err != nil {
+		t.Fatalf("error compressing object: %v", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("error compressing object: %v", err)
+	}
+
+	dirPath := path.Join(root, ".git", "objects", hash[:2])
+	err = os.MkdirAll(dirPath, 0755)
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+
+	err = os.WriteFile(path.Join(dirPath, hash[2:]), buf.Bytes(), 0444)
+	if err != nil {
+		t.Fatalf("error writing object: %v", err)
+	}
+
+	return hash
+}
+
+func TestValidateHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		hash  string
+		valid bool
+	}{
+		{name: "Accepts a full SHA-1 hash", hash: "d670460b4b4aece5915caf5c68d12f560a9fe3e4", valid: true},
+		{name: "Rejects a short hash", hash: "d670460", valid: false},
+		{name: "Rejects an empty hash", hash: "", valid: false},
+		{name: "Rejects non-hex characters", hash: "z670460b4b4aece5915caf5c68d12f560a9fe3e4", valid: false},
+		{name: "Rejects uppercase characters", hash: "D670460B4B4AECE5915CAF5C68D12F560A9FE3E4", valid: false},
+		{name: "Rejects a SHA-256 hash", hash: strings.Repeat("ab", 32), valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := git.ValidateHash(test.hash)
+			if test.valid && err != nil {
+				t.Fatalf("expected %s to be valid, got %v", test.hash, err)
+			}
+
+			if !test.valid && !errors.Is(err, git.ErrInvalidHash) {
+				t.Fatalf("expected error %v, got %v", git.ErrInvalidHash, err)
+			}
+		})
+	}
+}
+
+func TestEmptyObjectHashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{name: "SHA-1 tree", got: git.EmptyTreeHash(git.ObjectFormatSHA1), expected: "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+		{name: "SHA-1 blob", got: git.EmptyBlobHash(git.ObjectFormatSHA1), expected: "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{name: "SHA-256 tree", got: git.EmptyTreeHash(git.ObjectFormatSHA256), expected: "6ef19b41225c5369f1c104d45d8d85efa9b057b53b14b4b9b939dd74decc5321"},
+		{name: "SHA-256 blob", got: git.EmptyBlobHash(git.ObjectFormatSHA256), expected: "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, test.got)
+			}
+		})
+	}
+}
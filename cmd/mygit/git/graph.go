@@ -0,0 +1,114 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Graphviz writes the commit graph reachable from HEAD and every ref as a DOT
+// digraph: one node per commit, an edge to each parent and a box per ref.
+func (r *Repository) Graphviz(w io.Writer) error {
+	refs, err := r.Refs()
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if head.Hash != "" {
+		refs = append([]Ref{{Name: "HEAD", Hash: head.Hash}}, refs...)
+	}
+
+	/*
+		Refs may point at trees, blobs or tags, but only commits belong in the graph. Annotated
+		tags are peeled, so a tag of a commit is labelled like a lightweight one.
+	*/
+	var labels []Ref
+	var starts []string
+	for _, ref := range refs {
+		hash := ref.Hash
+		typ, _, err := r.ReadObjectHeader(hash)
+		for err == nil && typ == "tag" {
+			var body []byte
+			_, body, err = r.readObject(hash)
+			if err == nil {
+				hash, typ, err = parseTagTarget(body)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", ref.Name, err)
+		}
+		if typ != "commit" {
+			continue
+		}
+
+		labels = append(labels, Ref{Name: ref.Name, Hash: hash})
+		starts = append(starts, hash)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph commits {\n")
+
+	err = r.walkCommits(starts, func(commit Commit) error {
+		label := shortHash(commit.Hash) + " " + commit.Subject()
+		fmt.Fprintf(&sb, "\t%s [label=%s];\n", dotQuote(commit.Hash), dotQuote(label))
+		for _, parent := range commit.Parents {
+			fmt.Fprintf(&sb, "\t%s -> %s;\n", dotQuote(commit.Hash), dotQuote(parent))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range labels {
+		fmt.Fprintf(&sb, "\t%s [shape=box];\n", dotQuote(ref.Name))
+		fmt.Fprintf(&sb, "\t%s -> %s;\n", dotQuote(ref.Name), dotQuote(ref.Hash))
+	}
+
+	sb.WriteString("}\n")
+
+	_, err = io.WriteString(w, sb.String())
+	if err != nil {
+		return fmt.Errorf("failed to write the graph: %w", err)
+	}
+
+	return nil
+}
+
+// walkCommits visits every commit reachable from starts once, breadth first,
+// following all parents.
+func (r *Repository) walkCommits(starts []string, visit func(Commit) error) error {
+	seen := map[string]bool{}
+	queue := append([]string(nil), starts...)
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		commit, err := r.ReadCommit(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+
+		err = visit(commit)
+		if err != nil {
+			return err
+		}
+
+		queue = append(queue, commit.Parents...)
+	}
+
+	return nil
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,62 @@
+package git_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestGraphviz(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "base")
+	runGit(t, root, "checkout", "-q", "-b", "feature")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", `say "hi"`)
+	runGit(t, root, "checkout", "-q", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "mainline")
+	runGit(t, root, "merge", "-q", "--no-ff", "-m", "merge", "feature")
+	runGit(t, root, "tag", "v1", "HEAD~1")
+	runGit(t, root, "tag", "-a", "-m", "release", "v2", "feature")
+	runGit(t, root, "tag", "-a", "-m", "nested", "v2-nested", "v2")
+
+	base := runGit(t, root, "rev-parse", "HEAD~1~1")
+	feature := runGit(t, root, "rev-parse", "feature")
+	mainline := runGit(t, root, "rev-parse", "HEAD~1")
+	merge := runGit(t, root, "rev-parse", "HEAD")
+
+	repository := git.NewRepository(root)
+	var buf bytes.Buffer
+	err := repository.Graphviz(&buf)
+	if err != nil {
+		t.Fatalf("error writing graph: %v", err)
+	}
+	dot := buf.String()
+
+	if !strings.HasPrefix(dot, "digraph commits {\n") || !strings.HasSuffix(dot, "}\n") {
+		t.Fatalf("expected a digraph, got:\n%s", dot)
+	}
+
+	expected := []string{
+		`"` + feature + `" [label="` + feature[:7] + ` say \"hi\""];`,
+		`"` + merge + `" -> "` + mainline + `";`,
+		`"` + merge + `" -> "` + feature + `";`,
+		`"` + mainline + `" -> "` + base + `";`,
+		`"` + feature + `" -> "` + base + `";`,
+		`"HEAD" -> "` + merge + `";`,
+		`"refs/heads/feature" -> "` + feature + `";`,
+		`"refs/tags/v1" -> "` + mainline + `";`,
+		`"refs/tags/v2" -> "` + feature + `";`,
+		`"refs/tags/v2-nested" -> "` + feature + `";`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(dot, "\t"+line+"\n") {
+			t.Fatalf("expected the graph to contain %s, got:\n%s", line, dot)
+		}
+	}
+
+	if strings.Count(dot, "[label=") != 4 {
+		t.Fatalf("expected each of the 4 commits once, got:\n%s", dot)
+	}
+}
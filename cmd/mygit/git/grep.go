@@ -0,0 +1,80 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"regexp"
+)
+
+type GrepHit struct {
+	Path string
+	Line int
+	Text string
+}
+
+// Grep searches the files of treeish, which may be a tree, a commit or a ref,
+// for lines matching pattern.
+func (r *Repository) Grep(pattern, treeish string) ([]GrepHit, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	treeHash, err := r.ResolveTree(treeish)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, err := r.TreeFS(treeHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree %s: %w", treeHash, err)
+	}
+
+	var hits []GrepHit
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		contents, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", p, err)
+		}
+
+		if isBinary(contents) {
+			return nil
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(contents))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(contents)+1)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if re.Match(scanner.Bytes()) {
+				hits = append(hits, GrepHit{Path: p, Line: line, Text: scanner.Text()})
+			}
+		}
+
+		return scanner.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// isBinary uses the same heuristic as git: a NUL byte within the first 8000 bytes.
+func isBinary(contents []byte) bool {
+	if len(contents) > 8000 {
+		contents = contents[:8000]
+	}
+
+	return bytes.IndexByte(contents, 0) != -1
+}
@@ -0,0 +1,82 @@
+package git_test
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestGrep(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	files := map[string]string{
+		"main.go":        "package main\n\nfunc main() {\n\tprintln(\"Hello\")\n}\n",
+		"docs/readme.md": "# hello\nsay hello to everyone\n",
+		"image.bin":      "hello\x00world",
+	}
+	for name, contents := range files {
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+	runGit(t, root, "add", ".")
+	treeHash := runGit(t, root, "write-tree")
+
+	repository := git.NewRepository(root)
+
+	t.Run("Finds matches in nested text blobs and skips binary ones", func(t *testing.T) {
+		hits, err := repository.Grep("hello", treeHash)
+		if err != nil {
+			t.Fatalf("error grepping: %v", err)
+		}
+
+		expected := []git.GrepHit{
+			{Path: "docs/readme.md", Line: 1, Text: "# hello"},
+			{Path: "docs/readme.md", Line: 2, Text: "say hello to everyone"},
+		}
+		if !reflect.DeepEqual(hits, expected) {
+			t.Fatalf("expected %v, got %v", expected, hits)
+		}
+	})
+
+	t.Run("Matches case-insensitively", func(t *testing.T) {
+		hits, err := repository.Grep("(?i)HELLO\"", treeHash)
+		if err != nil {
+			t.Fatalf("error grepping: %v", err)
+		}
+
+		expected := []git.GrepHit{
+			{Path: "main.go", Line: 4, Text: "\tprintln(\"Hello\")"},
+		}
+		if !reflect.DeepEqual(hits, expected) {
+			t.Fatalf("expected %v, got %v", expected, hits)
+		}
+	})
+
+	t.Run("Resolves HEAD to the tree of the commit", func(t *testing.T) {
+		runGit(t, root, "commit", "-q", "-m", "initial")
+
+		hits, err := repository.Grep("hello", "HEAD")
+		if err != nil {
+			t.Fatalf("error grepping: %v", err)
+		}
+
+		expected := []git.GrepHit{
+			{Path: "docs/readme.md", Line: 1, Text: "# hello"},
+			{Path: "docs/readme.md", Line: 2, Text: "say hello to everyone"},
+		}
+		if !reflect.DeepEqual(hits, expected) {
+			t.Fatalf("expected %v, got %v", expected, hits)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const ErrUnbornBranch = Error("branch has no commits yet")
+
+type HeadState struct {
+	/*
+		Symbolic is true when HEAD points at a branch rather than directly at a commit.
+	*/
+	Symbolic bool
+	Ref      string
+	/*
+		Hash is empty when HEAD points at a branch that has no commits yet.
+	*/
+	Hash string
+}
+
+func (h HeadState) Detached() bool {
+	return !h.Symbolic
+}
+
+func (h HeadState) Unborn() bool {
+	return h.Symbolic && h.Hash == ""
+}
+
+func (r *Repository) Head() (HeadState, error) {
+	value, err := r.storage.ReadRef("HEAD")
+	if err != nil {
+		return HeadState{}, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	if !strings.HasPrefix(value, "ref: ") {
+		err := ValidateHash(value)
+		if err != nil {
+			return HeadState{}, fmt.Errorf("HEAD is neither a ref nor a commit: %w", err)
+		}
+
+		return HeadState{Hash: value}, nil
+	}
+
+	head := HeadState{Symbolic: true, Ref: strings.TrimPrefix(value, "ref: ")}
+	hash, err := r.resolveRef(head.Ref)
+	if err != nil {
+		if errors.Is(err, ErrRefNotFound) {
+			return head, nil
+		}
+
+		return HeadState{}, fmt.Errorf("failed to resolve %s: %w", head.Ref, err)
+	}
+
+	head.Hash = hash
+	return head, nil
+}
+
+// ResolveHead returns the commit HEAD points at, or ErrUnbornBranch when HEAD
+// names a branch that has no commits yet.
+func (r *Repository) ResolveHead() (string, error) {
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+
+	if head.Unborn() {
+		return "", fmt.Errorf("%w: %s", ErrUnbornBranch, head.Ref)
+	}
+
+	return head.Hash, nil
+}
+
+// DetachHead points HEAD directly at commit, which may be any revision that
+// resolves to a commit, and records the move in HEAD's reflog.
+func (r *Repository) DetachHead(commit string) error {
+	hash, err := r.RevParse(commit)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", commit, err)
+	}
+
+	_, err = r.ReadCommit(hash)
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	from, previous := head.Hash, head.Hash
+	if head.Symbolic {
+		from = strings.TrimPrefix(head.Ref, "refs/heads/")
+	}
+	if previous == "" {
+		previous = zeroHash
+	}
+
+	committer, err := r.defaultSignature("committer")
+	if err != nil {
+		return err
+	}
+
+	err = r.storage.WriteRef("HEAD", hash)
+	if err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+
+	return r.appendReflog("HEAD", reflogEntry{
+		old:       previous,
+		new:       hash,
+		committer: committer,
+		message:   fmt.Sprintf("checkout: moving from %s to %s", from, commit),
+	})
+}
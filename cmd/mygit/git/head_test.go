@@ -0,0 +1,166 @@
+package git_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestHead(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	repository := git.NewRepository(root)
+
+	t.Run("Reports an unborn branch", func(t *testing.T) {
+		head, err := repository.Head()
+		if err != nil {
+			t.Fatalf("error reading HEAD: %v", err)
+		}
+
+		expected := git.HeadState{Symbolic: true, Ref: "refs/heads/main"}
+		if head != expected || !head.Unborn() {
+			t.Fatalf("expected %+v, got %+v", expected, head)
+		}
+	})
+
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "initial")
+	commit := runGit(t, root, "rev-parse", "HEAD")
+
+	t.Run("Reports a symbolic HEAD", func(t *testing.T) {
+		head, err := repository.Head()
+		if err != nil {
+			t.Fatalf("error reading HEAD: %v", err)
+		}
+
+		expected := git.HeadState{Symbolic: true, Ref: "refs/heads/main", Hash: commit}
+		if head != expected || head.Unborn() || head.Detached() {
+			t.Fatalf("expected %+v, got %+v", expected, head)
+		}
+	})
+
+	t.Run("Reports a detached HEAD", func(t *testing.T) {
+		runGit(t, root, "checkout", "-q", "--detach")
+
+		head, err := repository.Head()
+		if err != nil {
+			t.Fatalf("error reading HEAD: %v", err)
+		}
+
+		expected := git.HeadState{Hash: commit}
+		if head != expected || !head.Detached() {
+			t.Fatalf("expected %+v, got %+v", expected, head)
+		}
+	})
+}
+
+func TestResolveHead(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	t.Run("Fails with ErrUnbornBranch before the first commit", func(t *testing.T) {
+		_, err := repository.ResolveHead()
+		if !errors.Is(err, git.ErrUnbornBranch) {
+			t.Fatalf("expected error %v, got %v", git.ErrUnbornBranch, err)
+		}
+
+		_, err = repository.RevParse("HEAD")
+		if !errors.Is(err, git.ErrUnbornBranch) {
+			t.Fatalf("expected rev-parse to fail with %v, got %v", git.ErrUnbornBranch, err)
+		}
+
+		_, err = repository.Log("HEAD", 0)
+		if !errors.Is(err, git.ErrUnbornBranch) {
+			t.Fatalf("expected log to fail with %v, got %v", git.ErrUnbornBranch, err)
+		}
+	})
+
+	t.Run("Resolves HEAD once the branch has a commit", func(t *testing.T) {
+		runGit(t, root, "commit", "-q", "--allow-empty", "-m", "initial")
+		expected := runGit(t, root, "rev-parse", "HEAD")
+
+		hash, err := repository.ResolveHead()
+		if err != nil {
+			t.Fatalf("error resolving HEAD: %v", err)
+		}
+
+		if hash != expected {
+			t.Fatalf("expected %s, got %s", expected, hash)
+		}
+	})
+}
+
+func TestDetachHead(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "config", "user.name", "Test")
+	runGit(t, root, "config", "user.email", "test@example.com")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "first")
+	first := runGit(t, root, "rev-parse", "HEAD")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "second")
+	repository := git.NewRepository(root)
+
+	t.Run("Detaches at a commit", func(t *testing.T) {
+		err := repository.DetachHead(first)
+		if err != nil {
+			t.Fatalf("error detaching HEAD: %v", err)
+		}
+
+		head, err := repository.Head()
+		if err != nil {
+			t.Fatalf("error reading HEAD: %v", err)
+		}
+
+		expected := git.HeadState{Hash: first}
+		if head != expected || !head.Detached() {
+			t.Fatalf("expected %+v, got %+v", expected, head)
+		}
+
+		message := runGit(t, root, "log", "-g", "-1", "--format=%gs", "HEAD")
+		if message != "checkout: moving from main to "+first {
+			t.Fatalf("expected a checkout reflog entry, got %q", message)
+		}
+	})
+
+	t.Run("Resolves branch names", func(t *testing.T) {
+		err := repository.DetachHead("main")
+		if err != nil {
+			t.Fatalf("error detaching HEAD: %v", err)
+		}
+
+		head, err := repository.Head()
+		if err != nil {
+			t.Fatalf("error reading HEAD: %v", err)
+		}
+
+		expected := runGit(t, root, "rev-parse", "main")
+		if head.Hash != expected || !head.Detached() {
+			t.Fatalf("expected HEAD detached at %s, got %+v", expected, head)
+		}
+
+		previous := runGit(t, root, "rev-parse", "HEAD@{1}")
+		if previous != first {
+			t.Fatalf("expected the reflog to record the move from %s, got %s", first, previous)
+		}
+	})
+
+	t.Run("Refuses objects that are not commits", func(t *testing.T) {
+		err := repository.DetachHead(runGit(t, root, "rev-parse", "HEAD^{tree}"))
+		if err == nil {
+			t.Fatalf("expected detaching at a tree to fail")
+		}
+
+		head, err := repository.Head()
+		if err != nil {
+			t.Fatalf("error reading HEAD: %v", err)
+		}
+
+		if head.Hash != runGit(t, root, "rev-parse", "main") {
+			t.Fatalf("expected HEAD to stay put, got %+v", head)
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type IgnoreMatch struct {
+	Path    string
+	Pattern string
+	Source  string
+	Line    int
+}
+
+func (r *Repository) CheckIgnore(paths []string) ([]IgnoreMatch, error) {
+	matcher := newIgnoreMatcher(r.root, r.infoExcludePath())
+
+	var matches []IgnoreMatch
+	for _, p := range paths {
+		cleaned := path.Clean(strings.TrimSuffix(p, "/"))
+		isDir := strings.HasSuffix(p, "/")
+		if !isDir {
+			fi, err := os.Stat(filepath.Join(r.root, filepath.FromSlash(cleaned)))
+			isDir = err == nil && fi.IsDir()
+		}
+
+		pattern, err := matcher.match(cleaned, isDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match %s: %w", p, err)
+		}
+
+		if pattern == nil || pattern.negate {
+			continue
+		}
+
+		matches = append(matches, IgnoreMatch{
+			Path:    p,
+			Pattern: pattern.text,
+			Source:  pattern.source,
+			Line:    pattern.line,
+		})
+	}
+
+	return matches, nil
+}
+
+// WalkWorkTree calls visit for every file and directory in the working tree,
+// in lexical order, with paths relative to the root and separated by slashes.
+// It skips .git and anything the ignore rules exclude. As with fs.WalkDir,
+// visit can return fs.SkipDir to leave a directory out.
+func (r *Repository) WalkWorkTree(visit func(relPath string, d fs.DirEntry) error) error {
+	matcher := newIgnoreMatcher(r.root, r.infoExcludePath())
+
+	return filepath.WalkDir(r.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(r.root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+
+		pattern, err := matcher.match(rel, d.IsDir())
+		if err != nil {
+			return fmt.Errorf("failed to match %s: %w", rel, err)
+		}
+
+		if pattern != nil && !pattern.negate {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		return visit(rel, d)
+	})
+}
+
+// ignored reports whether the file or directory at filePath, below the root of
+// the matcher, is excluded by the ignore rules.
+func (m *ignoreMatcher) ignored(filePath string, isDir bool) (bool, error) {
+	rel, err := filepath.Rel(m.root, filePath)
+	if err != nil {
+		return false, err
+	}
+
+	pattern, err := m.match(filepath.ToSlash(rel), isDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to match %s: %w", rel, err)
+	}
+
+	return pattern != nil && !pattern.negate, nil
+}
+
+type ignorePattern struct {
+	text     string
+	source   string
+	line     int
+	base     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	re       *regexp.Regexp
+}
+
+func (p *ignorePattern) matches(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(relPath, p.base+"/") {
+			return false
+		}
+		relPath = strings.TrimPrefix(relPath, p.base+"/")
+	}
+
+	if !p.anchored {
+		relPath = path.Base(relPath)
+	}
+
+	return p.re.MatchString(relPath)
+}
+
+type ignoreMatcher struct {
+	root        string
+	excludePath string
+	patterns    map[string][]ignorePattern
+}
+
+func newIgnoreMatcher(root string, excludePath string) *ignoreMatcher {
+	return &ignoreMatcher{root: root, excludePath: excludePath, patterns: map[string][]ignorePattern{}}
+}
+
+// match returns the pattern deciding the fate of relPath, or nil if nothing matches.
+// A returned negated pattern means the path was explicitly re-included.
+func (m *ignoreMatcher) match(relPath string, isDir bool) (*ignorePattern, error) {
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		pattern, err := m.matchOne(strings.Join(parts[:i], "/"), true)
+		if err != nil {
+			return nil, err
+		}
+
+		/*
+			Git cannot re-include a file whose parent directory is excluded.
+		*/
+		if pattern != nil && !pattern.negate {
+			return pattern, nil
+		}
+	}
+
+	return m.matchOne(relPath, isDir)
+}
+
+func (m *ignoreMatcher) matchOne(relPath string, isDir bool) (*ignorePattern, error) {
+	dirs := []string{""}
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+
+	var candidates []ignorePattern
+	excludes, err := m.load(m.excludePath, "info/exclude", "")
+	if err != nil {
+		return nil, err
+	}
+	candidates = append(candidates, excludes...)
+
+	for _, dir := range dirs {
+		source := path.Join(dir, ".gitignore")
+		patterns, err := m.load(filepath.Join(m.root, filepath.FromSlash(source)), source, dir)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, patterns...)
+	}
+
+	/*
+		Deeper files come last and within a file the last matching line wins, so walk backwards.
+	*/
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if candidates[i].matches(relPath, isDir) {
+			return &candidates[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *ignoreMatcher) load(filePath string, source string, base string) ([]ignorePattern, error) {
+	if patterns, ok := m.patterns[filePath]; ok {
+		return patterns, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			m.patterns[filePath] = nil
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer file.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		pattern, ok := parseIgnorePattern(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		pattern.source = source
+		pattern.line = line
+		pattern.base = base
+		patterns = append(patterns, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	m.patterns[filePath] = patterns
+	return patterns, nil
+}
+
+func parseIgnorePattern(text string) (ignorePattern, bool) {
+	pattern := ignorePattern{text: text}
+
+	glob := strings.TrimRight(text, " \t")
+	if glob == "" || strings.HasPrefix(glob, "#") {
+		return pattern, false
+	}
+	pattern.text = glob
+
+	if strings.HasPrefix(glob, "!") {
+		pattern.negate = true
+		glob = glob[1:]
+	} else if strings.HasPrefix(glob, `\!`) || strings.HasPrefix(glob, `\#`) {
+		glob = glob[1:]
+	}
+
+	if strings.HasSuffix(glob, "/") {
+		pattern.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+
+	if strings.Contains(glob, "/") {
+		pattern.anchored = true
+		glob = strings.TrimPrefix(glob, "/")
+	}
+
+	if glob == "" {
+		return pattern, false
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(glob) + "$")
+	if err != nil {
+		return pattern, false
+	}
+	pattern.re = re
+
+	return pattern, true
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,134 @@
+package git_test
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestCheckIgnore(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":        "*.log\nbuild/\n!keep.log\n",
+		"nested/.gitignore": "!debug.log\n/local.txt\n",
+	}
+	for name, contents := range files {
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	err := os.MkdirAll(path.Join(root, "build"), 0755)
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+
+	repository := git.NewRepository(root)
+
+	tests := []struct {
+		name    string
+		path    string
+		ignored bool
+		source  string
+		line    int
+	}{
+		{name: "Matches a glob", path: "error.log", ignored: true, source: ".gitignore", line: 1},
+		{name: "Does not match an unrelated path", path: "main.go", ignored: false},
+		{name: "Respects a negated pattern", path: "keep.log", ignored: false},
+		{name: "Ignores files inside an ignored directory", path: "build/out.bin", ignored: true, source: ".gitignore", line: 2},
+		{name: "Lets a nested file override the root file", path: "nested/debug.log", ignored: false},
+		{name: "Applies root patterns inside nested directories", path: "nested/trace.log", ignored: true, source: ".gitignore", line: 1},
+		{name: "Anchors patterns to the nested directory", path: "nested/local.txt", ignored: true, source: "nested/.gitignore", line: 2},
+		{name: "Does not apply anchored nested patterns elsewhere", path: "local.txt", ignored: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := repository.CheckIgnore([]string{tt.path})
+			if err != nil {
+				t.Fatalf("error checking ignore: %v", err)
+			}
+
+			if !tt.ignored {
+				if len(matches) != 0 {
+					t.Fatalf("expected %s not to be ignored, got %v", tt.path, matches)
+				}
+				return
+			}
+
+			if len(matches) != 1 {
+				t.Fatalf("expected %s to be ignored, got %v", tt.path, matches)
+			}
+
+			if matches[0].Source != tt.source || matches[0].Line != tt.line {
+				t.Fatalf("expected match from %s:%d, got %s:%d", tt.source, tt.line, matches[0].Source, matches[0].Line)
+			}
+		})
+	}
+}
+
+func TestWalkWorkTree(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	files := map[string]string{
+		".gitignore":            "*.log\nbuild/\n",
+		"main.go":               "package main",
+		"debug.log":             "ignored",
+		"build/out.bin":         "ignored",
+		"src/lib.go":            "package src",
+		"src/trace.log":         "ignored",
+		"src/deep/.gitignore":   "!keep.log\n",
+		"src/deep/keep.log":     "kept",
+		"src/deep/nested/a.txt": "a",
+	}
+	for name, contents := range files {
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	repository := git.NewRepository(root)
+
+	var visited []string
+	err := repository.WalkWorkTree(func(relPath string, d fs.DirEntry) error {
+		if d.IsDir() {
+			relPath += "/"
+		}
+		visited = append(visited, relPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error walking the working tree: %v", err)
+	}
+
+	expected := []string{
+		".gitignore",
+		"main.go",
+		"src/",
+		"src/deep/",
+		"src/deep/.gitignore",
+		"src/deep/keep.log",
+		"src/deep/nested/",
+		"src/deep/nested/a.txt",
+		"src/lib.go",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Fatalf("expected %v, got %v", expected, visited)
+	}
+}
@@ -0,0 +1,114 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (r *Repository) Log(start string, limit int) ([]Commit, error) {
+	var commits []Commit
+
+	next := start
+	for next != "" && (limit <= 0 || len(commits) < limit) {
+		commit, err := r.ReadCommit(next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read commit %s: %w", next, err)
+		}
+		commits = append(commits, commit)
+
+		next = ""
+		if len(commit.Parents) > 0 {
+			next = commit.Parents[0]
+		}
+	}
+
+	return commits, nil
+}
+
+func (c Commit) Subject() string {
+	subject, _, _ := strings.Cut(strings.TrimLeft(c.Message, "\n"), "\n")
+	return subject
+}
+
+func (c Commit) Body() string {
+	_, body, _ := strings.Cut(strings.TrimLeft(c.Message, "\n"), "\n")
+	return strings.TrimLeft(body, "\n")
+}
+
+func (c Commit) Format(spec string) string {
+	placeholders := []struct {
+		placeholder string
+		value       func() string
+	}{
+		{"%H", func() string { return c.Hash }},
+		{"%h", func() string { return shortHash(c.Hash) }},
+		{"%T", func() string { return c.Tree }},
+		{"%t", func() string { return shortHash(c.Tree) }},
+		{"%P", func() string { return strings.Join(c.Parents, " ") }},
+		{"%p", func() string {
+			var parents []string
+			for _, parent := range c.Parents {
+				parents = append(parents, shortHash(parent))
+			}
+			return strings.Join(parents, " ")
+		}},
+		{"%an", func() string { return c.Author.Name }},
+		{"%ae", func() string { return c.Author.Email }},
+		{"%ad", func() string { return formatDate(c.Author) }},
+		{"%at", func() string { return fmt.Sprint(c.Author.When.Unix()) }},
+		{"%cn", func() string { return c.Committer.Name }},
+		{"%ce", func() string { return c.Committer.Email }},
+		{"%cd", func() string { return formatDate(c.Committer) }},
+		{"%ct", func() string { return fmt.Sprint(c.Committer.When.Unix()) }},
+		{"%s", c.Subject},
+		{"%b", c.Body},
+		{"%n", func() string { return "\n" }},
+		{"%%", func() string { return "%" }},
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(spec); i++ {
+		matched := false
+		for _, p := range placeholders {
+			if strings.HasPrefix(spec[i:], p.placeholder) {
+				sb.WriteString(p.value())
+				i += len(p.placeholder) - 1
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			sb.WriteByte(spec[i])
+		}
+	}
+
+	return sb.String()
+}
+
+func (c Commit) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "commit %s\n", c.Hash)
+	if len(c.Parents) > 1 {
+		fmt.Fprintf(&sb, "Merge: %s\n", c.Format("%p"))
+	}
+	fmt.Fprintf(&sb, "Author: %s <%s>\n", c.Author.Name, c.Author.Email)
+	fmt.Fprintf(&sb, "Date:   %s\n\n", formatDate(c.Author))
+	for _, line := range strings.Split(strings.TrimRight(c.Message, "\n"), "\n") {
+		fmt.Fprintf(&sb, "    %s\n", line)
+	}
+
+	return sb.String()
+}
+
+func shortHash(hash string) string {
+	if len(hash) < 7 {
+		return hash
+	}
+
+	return hash[:7]
+}
+
+func formatDate(signature Signature) string {
+	return signature.When.Format("Mon Jan 2 15:04:05 2006 -0700")
+}
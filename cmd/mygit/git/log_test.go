@@ -0,0 +1,127 @@
+package git_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestLog(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+
+	for i, message := range []string{"first", "second", "third"} {
+		err := os.WriteFile(path.Join(root, "file.txt"), []byte(message), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+		runGit(t, root, "add", ".")
+		runGit(t, root, "commit", "-q", "-m", message, "-m", "body "+string(rune('a'+i)))
+	}
+
+	repository := git.NewRepository(root)
+
+	t.Run("Formats commits as oneline", func(t *testing.T) {
+		commits, err := repository.Log("HEAD", 0)
+		if err != nil {
+			t.Fatalf("error reading log: %v", err)
+		}
+
+		var out string
+		for _, commit := range commits {
+			out += commit.Format("%h %s") + "\n"
+		}
+
+		expected := runGit(t, root, "log", "--oneline") + "\n"
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	})
+
+	t.Run("Formats commits with custom placeholders", func(t *testing.T) {
+		commits, err := repository.Log("main", 2)
+		if err != nil {
+			t.Fatalf("error reading log: %v", err)
+		}
+
+		if len(commits) != 2 {
+			t.Fatalf("expected 2 commits, got %d", len(commits))
+		}
+
+		const format = "%H/%an/%ae/%s/%b/%P%%"
+		var out string
+		for _, commit := range commits {
+			out += commit.Format(format) + "\n"
+		}
+
+		expected := runGit(t, root, "log", "-n", "2", "--format="+format) + "\n"
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	})
+
+	t.Run("Matches the default git log output", func(t *testing.T) {
+		commits, err := repository.Log("HEAD", 0)
+		if err != nil {
+			t.Fatalf("error reading log: %v", err)
+		}
+
+		var out string
+		for i, commit := range commits {
+			if i > 0 {
+				out += "\n"
+			}
+			out += commit.String()
+		}
+
+		expected := runGit(t, root, "log") + "\n"
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	})
+}
+
+func TestCommitStat(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	write := func(name, contents string) {
+		t.Helper()
+		err := os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	write("modified.txt", "a\nb\nc\n")
+	write("removed.txt", "one\ntwo\n")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	write("modified.txt", "a\nB\nc\nd")
+	write("added.txt", "x\ny\n")
+	write("image.bin", "\x00\x01\x02\x03")
+	write("a-file-with-a-rather-long-name-to-force-the-histogram-to-scale.txt", strings.Repeat("line\n", 120))
+	os.Remove(path.Join(root, "removed.txt"))
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "change things")
+
+	repository := git.NewRepository(root)
+
+	for _, rev := range []string{"HEAD", "HEAD~1"} {
+		t.Run("Matches git's stat for "+rev, func(t *testing.T) {
+			stats, err := repository.CommitStat(runGit(t, root, "rev-parse", rev))
+			if err != nil {
+				t.Fatalf("error computing stat: %v", err)
+			}
+
+			expected := runGit(t, root, "show", "--stat", "--format=", rev)
+			if got := strings.TrimSpace(git.FormatStat(stats)); got != expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", expected, got)
+			}
+		})
+	}
+}
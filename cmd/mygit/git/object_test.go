@@ -0,0 +1,635 @@
+package git_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestOpenObject(t *testing.T) {
+	t.Run("Streams a large blob without loading it into memory", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		const size = 32 << 20
+		contents := bytes.Repeat([]byte("0123456789abcdef"), size/16)
+		err = os.WriteFile(path.Join(root, "large.bin"), contents, 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+
+		hash, err := repository.WriteBlob(os.DirFS(root), "large.bin")
+		if err != nil {
+			t.Fatalf("error writing blob: %v", err)
+		}
+		contents = nil
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		typ, declaredSize, rc, err := repository.OpenObject(hash)
+		if err != nil {
+			t.Fatalf("error opening object: %v", err)
+		}
+		n, err := io.Copy(io.Discard, rc)
+		if err != nil {
+			t.Fatalf("error streaming object: %v", err)
+		}
+		err = rc.Close()
+		if err != nil {
+			t.Fatalf("error closing object: %v", err)
+		}
+
+		runtime.ReadMemStats(&after)
+
+		if typ != "blob" {
+			t.Fatalf("expected type blob, got %s", typ)
+		}
+		if declaredSize != size || n != size {
+			t.Fatalf("expected %d bytes, got declared %d and streamed %d", size, declaredSize, n)
+		}
+
+		allocated := after.TotalAlloc - before.TotalAlloc
+		if allocated > size/8 {
+			t.Fatalf("expected streaming to allocate well under %d bytes, got %d", size, allocated)
+		}
+	})
+
+	t.Run("Round-trips objects stored without compression", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		repository.RawMode = true
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		hash, err := repository.WriteObject("blob", []byte("raw contents\n"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		onDisk, err := os.ReadFile(path.Join(root, ".git", "objects", hash[:2], hash[2:]))
+		if err != nil {
+			t.Fatalf("error reading object file: %v", err)
+		}
+
+		if string(onDisk) != "blob 13\x00raw contents\n" {
+			t.Fatalf("expected the object to be stored uncompressed, got %q", onDisk)
+		}
+
+		contents, err := repository.CatFile(hash)
+		if err != nil {
+			t.Fatalf("error reading object: %v", err)
+		}
+
+		if contents != "raw contents\n" {
+			t.Fatalf("expected raw contents, got %q", contents)
+		}
+
+		compressed := git.NewRepository(root)
+		contents, err = compressed.CatFile(hash)
+		if err != nil {
+			t.Fatalf("error reading raw object from a normal repository: %v", err)
+		}
+
+		if contents != "raw contents\n" {
+			t.Fatalf("expected raw contents, got %q", contents)
+		}
+	})
+
+	t.Run("Reads the header without inflating the whole object", func(t *testing.T) {
+		storage := &countingStorage{Storage: git.NewMemoryStorage()}
+		repository := git.NewRepositoryWithStorage(t.TempDir(), storage)
+
+		contents := make([]byte, 4<<20)
+		_, err := rand.Read(contents)
+		if err != nil {
+			t.Fatalf("error generating contents: %v", err)
+		}
+
+		hash, err := repository.WriteObject("blob", contents)
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		typ, size, err := repository.ReadObjectHeader(hash)
+		if err != nil {
+			t.Fatalf("error reading header: %v", err)
+		}
+
+		if typ != "blob" || size != int64(len(contents)) {
+			t.Fatalf("expected blob %d, got %s %d", len(contents), typ, size)
+		}
+
+		if storage.read > 64<<10 {
+			t.Fatalf("expected to read only the start of the object, read %d bytes", storage.read)
+		}
+	})
+
+	t.Run("Checks whether objects exist", func(t *testing.T) {
+		storage := git.NewMemoryStorage()
+		repository := git.NewRepositoryWithStorage(t.TempDir(), storage)
+
+		hash, err := repository.WriteObject("blob", []byte("present"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		if !repository.ObjectExists(hash) {
+			t.Fatalf("expected %s to exist", hash)
+		}
+
+		if repository.ObjectExists("0000000000000000000000000000000000000000") {
+			t.Fatalf("expected a missing object not to exist")
+		}
+
+		const corrupt = "1111111111111111111111111111111111111111"
+		err = storage.WriteObject(corrupt, []byte("not an object"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		if repository.ObjectExists(corrupt) {
+			t.Fatalf("expected a corrupt object not to count as existing")
+		}
+	})
+}
+
+func TestListObjects(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	var expected []string
+	for _, contents := range []string{"one", "two", "three"} {
+		hash, err := repository.WriteObject("blob", []byte(contents))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+		expected = append(expected, hash)
+	}
+	sort.Strings(expected)
+
+	noise := []string{"pack/pack-abc.pack", "info/packs", expected[0][:2] + "/tmp_obj_123"}
+	for _, name := range noise {
+		p := path.Join(root, ".git", "objects", name)
+		err := os.MkdirAll(path.Dir(p), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(p, []byte("noise"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	hashes, err := repository.ListObjects()
+	if err != nil {
+		t.Fatalf("error listing objects: %v", err)
+	}
+
+	if !reflect.DeepEqual(hashes, expected) {
+		t.Fatalf("expected %v, got %v", expected, hashes)
+	}
+}
+
+type countingStorage struct {
+	git.Storage
+	read   int64
+	writes map[string]int
+}
+
+func (s *countingStorage) ReadObject(hash string) (io.ReadCloser, error) {
+	rc, err := s.Storage.ReadObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingReader{ReadCloser: rc, read: &s.read}, nil
+}
+
+func (s *countingStorage) WriteObject(hash string, contents []byte) error {
+	if s.writes == nil {
+		s.writes = map[string]int{}
+	}
+	s.writes[hash]++
+
+	return s.Storage.WriteObject(hash, contents)
+}
+
+type countingReader struct {
+	io.ReadCloser
+	read *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	*c.read += int64(n)
+	return n, err
+}
+
+func TestObjectPath(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	hash, err := repository.WriteObject("blob", []byte("test content\n"))
+	if err != nil {
+		t.Fatalf("error writing object: %v", err)
+	}
+	expected := path.Join(root, ".git", "objects", hash[:2], hash[2:])
+
+	for _, name := range []string{hash, hash[:7], hash[:4]} {
+		t.Run("Resolves "+name, func(t *testing.T) {
+			objectPath, err := repository.ObjectPath(name)
+			if err != nil {
+				t.Fatalf("error resolving object path: %v", err)
+			}
+
+			if objectPath != expected {
+				t.Fatalf("expected %s, got %s", expected, objectPath)
+			}
+		})
+	}
+
+	t.Run("Fails for an unknown hash", func(t *testing.T) {
+		_, err := repository.ObjectPath("0000000")
+		if !errors.Is(err, git.ErrObjectNotFound) {
+			t.Fatalf("expected error %v, got %v", git.ErrObjectNotFound, err)
+		}
+	})
+
+	t.Run("Fails for a prefix that is too short", func(t *testing.T) {
+		_, err := repository.ObjectPath(hash[:3])
+		if !errors.Is(err, git.ErrInvalidHash) {
+			t.Fatalf("expected error %v, got %v", git.ErrInvalidHash, err)
+		}
+	})
+
+	t.Run("Fails for a repository kept in memory", func(t *testing.T) {
+		repository := git.NewRepositoryWithStorage(t.TempDir(), git.NewMemoryStorage())
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		hash, err := repository.WriteObject("blob", []byte("test content\n"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		_, err = repository.ObjectPath(hash)
+		if !errors.Is(err, git.ErrNoObjectPath) {
+			t.Fatalf("expected error %v, got %v", git.ErrNoObjectPath, err)
+		}
+	})
+}
+
+func TestEachTreeEntry(t *testing.T) {
+	storage := &countingStorage{Storage: git.NewMemoryStorage()}
+	repository := git.NewRepositoryWithStorage(t.TempDir(), storage)
+
+	/*
+		Random hashes keep the tree from compressing well, so reading all of it takes many buffer fills.
+	*/
+	const count = 2000
+	var body []byte
+	for i := 0; i < count; i++ {
+		rawHash := make([]byte, 20)
+		_, err := rand.Read(rawHash)
+		if err != nil {
+			t.Fatalf("error generating hash: %v", err)
+		}
+
+		body = append(body, fmt.Sprintf("100644 file%04d\x00", i)...)
+		body = append(body, rawHash...)
+	}
+
+	hash, err := repository.WriteObject("tree", body)
+	if err != nil {
+		t.Fatalf("error writing tree: %v", err)
+	}
+
+	var fullRead int64
+	t.Run("Visits every entry", func(t *testing.T) {
+		storage.read = 0
+		var names []string
+		err := repository.EachTreeEntry(hash, func(entry git.TreeEntry) error {
+			names = append(names, entry.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("error iterating tree: %v", err)
+		}
+
+		if len(names) != count || names[0] != "file0000" || names[count-1] != "file1999" {
+			t.Fatalf("expected %d entries from file0000 to file1999, got %d", count, len(names))
+		}
+		fullRead = storage.read
+	})
+
+	t.Run("Stops when the callback fails", func(t *testing.T) {
+		storage.read = 0
+		errStop := errors.New("stop")
+
+		var visited int
+		err := repository.EachTreeEntry(hash, func(entry git.TreeEntry) error {
+			visited++
+			if visited == 3 {
+				return errStop
+			}
+			return nil
+		})
+		if !errors.Is(err, errStop) {
+			t.Fatalf("expected the callback error, got %v", err)
+		}
+
+		if visited != 3 {
+			t.Fatalf("expected 3 entries to be visited, got %d", visited)
+		}
+
+		if storage.read >= fullRead/2 {
+			t.Fatalf("expected only the start of the tree to be read, read %d bytes", storage.read)
+		}
+	})
+
+	t.Run("Fails on a truncated tree", func(t *testing.T) {
+		truncated, err := repository.WriteObject("tree", body[:len(body)-5])
+		if err != nil {
+			t.Fatalf("error writing tree: %v", err)
+		}
+
+		err = repository.EachTreeEntry(truncated, func(git.TreeEntry) error { return nil })
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+		}
+	})
+}
+
+func TestImportObject(t *testing.T) {
+	source := t.TempDir()
+	runGit(t, source, "init", "-q")
+	runGit(t, source, "commit", "-q", "--allow-empty", "-m", "initial")
+	commit := runGit(t, source, "rev-parse", "HEAD")
+	payload := []byte(runGit(t, source, "cat-file", "commit", commit) + "\n")
+
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	t.Run("Imports an object matching the expected hash", func(t *testing.T) {
+		hash, err := repository.ImportObject("commit", payload, commit)
+		if err != nil {
+			t.Fatalf("error importing object: %v", err)
+		}
+
+		if hash != commit {
+			t.Fatalf("expected %s, got %s", commit, hash)
+		}
+
+		typ := runGit(t, root, "cat-file", "-t", hash)
+		if typ != "commit" {
+			t.Fatalf("expected git to read a commit, got %s", typ)
+		}
+	})
+
+	t.Run("Rejects an object that does not match", func(t *testing.T) {
+		tampered := bytes.Replace(payload, []byte("initial"), []byte("tampered"), 1)
+		_, err := repository.ImportObject("commit", tampered, commit)
+		if !errors.Is(err, git.ErrHashMismatch) {
+			t.Fatalf("expected error %v, got %v", git.ErrHashMismatch, err)
+		}
+
+		hashes, err := repository.ListObjects()
+		if err != nil {
+			t.Fatalf("error listing objects: %v", err)
+		}
+
+		if len(hashes) != 1 {
+			t.Fatalf("expected only the imported commit to be stored, got %v", hashes)
+		}
+	})
+
+	t.Run("Skips the check without an expected hash", func(t *testing.T) {
+		hash, err := repository.ImportObject("blob", []byte("test content\n"), "")
+		if err != nil {
+			t.Fatalf("error importing object: %v", err)
+		}
+
+		if hash != "d670460b4b4aece5915caf5c68d12f560a9fe3e4" {
+			t.Fatalf("expected the canonical blob hash, got %s", hash)
+		}
+	})
+}
+
+const errInjected = git.Error("injected failure")
+
+// failingStorage fails every object write after the first allowed ones.
+type failingStorage struct {
+	git.Storage
+	allowed int
+}
+
+func (s *failingStorage) WriteObject(hash string, contents []byte) error {
+	if s.allowed == 0 {
+		return errInjected
+	}
+	s.allowed--
+
+	return s.Storage.WriteObject(hash, contents)
+}
+
+func TestObjectReader(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	contents := make([]byte, 3<<20+17)
+	_, err = rand.Read(contents)
+	if err != nil {
+		t.Fatalf("error generating contents: %v", err)
+	}
+
+	hash, err := repository.WriteObject("blob", contents)
+	if err != nil {
+		t.Fatalf("error writing object: %v", err)
+	}
+
+	rc, err := repository.ObjectReader(hash)
+	if err != nil {
+		t.Fatalf("error opening object: %v", err)
+	}
+	defer rc.Close()
+
+	/*
+		Keep only the ends of the stream so the whole blob never sits in memory twice.
+	*/
+	var first, last [1]byte
+	var count int64
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := rc.Read(buf)
+		if n > 0 {
+			if count == 0 {
+				first[0] = buf[0]
+			}
+			last[0] = buf[n-1]
+			count += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("error streaming object: %v", err)
+		}
+	}
+
+	if count != int64(len(contents)) {
+		t.Fatalf("expected %d bytes, got %d", len(contents), count)
+	}
+
+	if first[0] != contents[0] || last[0] != contents[len(contents)-1] {
+		t.Fatalf("expected the stream to start with %x and end with %x, got %x and %x", contents[0], contents[len(contents)-1], first[0], last[0])
+	}
+}
+
+func TestPooledObjectReaders(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	contents := map[string]string{}
+	for i := 0; i < 64; i++ {
+		body := fmt.Sprintf("object %d\n%s", i, bytes.Repeat([]byte{byte('a' + i%26)}, i*97))
+		hash, err := repository.WriteObject("blob", []byte(body))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+		contents[hash] = body
+	}
+
+	readAll := func(hash string) (string, error) {
+		rc, err := repository.ObjectReader(hash)
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+
+		body, err := io.ReadAll(rc)
+		return string(body), err
+	}
+
+	t.Run("Reads each object whole after a partial read of another", func(t *testing.T) {
+		for hash, expected := range contents {
+			rc, err := repository.ObjectReader(hash)
+			if err != nil {
+				t.Fatalf("error opening %s: %v", hash, err)
+			}
+
+			_, err = rc.Read(make([]byte, 3))
+			if err != nil {
+				t.Fatalf("error reading %s: %v", hash, err)
+			}
+
+			err = rc.Close()
+			if err != nil {
+				t.Fatalf("error closing %s: %v", hash, err)
+			}
+
+			_, err = rc.Read(make([]byte, 1))
+			if !errors.Is(err, os.ErrClosed) {
+				t.Fatalf("expected reading a closed object to fail with %v, got %v", os.ErrClosed, err)
+			}
+
+			body, err := readAll(hash)
+			if err != nil {
+				t.Fatalf("error reading %s: %v", hash, err)
+			}
+
+			if body != expected {
+				t.Fatalf("expected %q, got %q", expected, body)
+			}
+		}
+	})
+
+	t.Run("Reads objects concurrently", func(t *testing.T) {
+		errs := make(chan error, len(contents))
+		for hash, expected := range contents {
+			go func(hash, expected string) {
+				body, err := readAll(hash)
+				if err == nil && body != expected {
+					err = fmt.Errorf("expected %q for %s, got %q", expected, hash, body)
+				}
+				errs <- err
+			}(hash, expected)
+		}
+
+		for range contents {
+			err := <-errs
+			if err != nil {
+				t.Fatalf("error reading concurrently: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkReadObjects(b *testing.B) {
+	root := b.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		b.Fatalf("error initializing repository: %v", err)
+	}
+
+	var hashes []string
+	for i := 0; i < 1000; i++ {
+		hash, err := repository.WriteObject("blob", []byte(fmt.Sprintf("object %d\n", i)))
+		if err != nil {
+			b.Fatalf("error writing object: %v", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, hash := range hashes {
+			_, _, err := repository.ReadObjectHeader(hash)
+			if err != nil {
+				b.Fatalf("error reading %s: %v", hash, err)
+			}
+		}
+	}
+}
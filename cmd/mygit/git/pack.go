@@ -0,0 +1,155 @@
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+var packObjectTypes = map[string]byte{
+	"commit": 1,
+	"tree":   2,
+	"blob":   3,
+	"tag":    4,
+}
+
+const objRefDelta = 7
+
+// PackObject names an object to pack. Path is optional and only steers the
+// choice of delta bases: blobs at the same path are tried first.
+type PackObject struct {
+	Hash string
+	Path string
+}
+
+// WritePack writes the given objects to w as a version 2 packfile and returns
+// the hex SHA-1 checksum that trails it. Every object is stored whole.
+func (r *Repository) WritePack(w io.Writer, objects []string) (string, error) {
+	packObjects := make([]PackObject, len(objects))
+	for i, hash := range objects {
+		packObjects[i] = PackObject{Hash: hash}
+	}
+
+	return r.writePack(w, packObjects, false)
+}
+
+// WriteDeltaPack is like WritePack, but stores blobs as deltas against similar
+// blobs written earlier in the pack when that makes them smaller.
+func (r *Repository) WriteDeltaPack(w io.Writer, objects []PackObject) (string, error) {
+	return r.writePack(w, objects, true)
+}
+
+func (r *Repository) writePack(w io.Writer, objects []PackObject, deltas bool) (string, error) {
+	hasher := sha1.New()
+	pw := io.MultiWriter(w, hasher)
+
+	header := make([]byte, 12)
+	copy(header, "PACK")
+	binary.BigEndian.PutUint32(header[4:], 2)
+	binary.BigEndian.PutUint32(header[8:], uint32(len(objects)))
+	_, err := pw.Write(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to write the pack header: %w", err)
+	}
+
+	bases := newDeltaBases()
+	for _, object := range objects {
+		typ, body, err := r.readObject(object.Hash)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", object.Hash, err)
+		}
+
+		if deltas && typ == "blob" {
+			base, delta := bases.find(object, body)
+			bases.add(object, body)
+
+			if base != "" {
+				err = writeRefDeltaEntry(pw, base, delta)
+				if err != nil {
+					return "", fmt.Errorf("failed to write %s: %w", object.Hash, err)
+				}
+				continue
+			}
+		}
+
+		err = writePackEntry(pw, packObjectTypes[typ], int64(len(body)), body)
+		if err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", object.Hash, err)
+		}
+	}
+
+	checksum := hasher.Sum(nil)
+	_, err = w.Write(checksum)
+	if err != nil {
+		return "", fmt.Errorf("failed to write the pack checksum: %w", err)
+	}
+
+	return fmt.Sprintf("%x", checksum), nil
+}
+
+func writeRefDeltaEntry(w io.Writer, base string, delta []byte) error {
+	rawBase, err := hex.DecodeString(base)
+	if err != nil {
+		return fmt.Errorf("failed to decode base %s: %w", base, err)
+	}
+
+	_, err = w.Write(packEntryHeader(objRefDelta, int64(len(delta))))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(rawBase)
+	if err != nil {
+		return err
+	}
+
+	return writeCompressed(w, delta)
+}
+
+// writePackEntry writes the type and size header followed by the compressed
+// data. The size is that of the data before compression.
+func writePackEntry(w io.Writer, typ byte, size int64, data []byte) error {
+	_, err := w.Write(packEntryHeader(typ, size))
+	if err != nil {
+		return err
+	}
+
+	return writeCompressed(w, data)
+}
+
+func writeCompressed(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err := zw.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// packEntryHeader encodes the type in bits 4-6 of the first byte and the size
+// as a little-endian varint: 4 bits in the first byte, then 7 bits per byte.
+func packEntryHeader(typ byte, size int64) []byte {
+	b := typ<<4 | byte(size&0x0f)
+	size >>= 4
+
+	var header []byte
+	for size > 0 {
+		header = append(header, b|0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+
+	return append(header, b)
+}
@@ -0,0 +1,156 @@
+package git_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestWritePack(t *testing.T) {
+	t.Run("Writes a pack that git can index", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		var objects []string
+		for _, contents := range []string{"small\n", strings.Repeat("a larger blob\n", 100)} {
+			hash, err := repository.WriteObject("blob", []byte(contents))
+			if err != nil {
+				t.Fatalf("error writing blob: %v", err)
+			}
+			objects = append(objects, hash)
+		}
+
+		err = os.WriteFile(path.Join(root, "file.txt"), []byte("small\n"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+
+		tree, err := repository.WriteTree(root)
+		if err != nil {
+			t.Fatalf("error writing tree: %v", err)
+		}
+		objects = append(objects, tree)
+
+		packPath := path.Join(t.TempDir(), "test.pack")
+		packFile, err := os.Create(packPath)
+		if err != nil {
+			t.Fatalf("error creating pack: %v", err)
+		}
+
+		checksum, err := repository.WritePack(packFile, objects)
+		packFile.Close()
+		if err != nil {
+			t.Fatalf("error writing pack: %v", err)
+		}
+
+		indexed := runGit(t, root, "index-pack", packPath)
+		if indexed != checksum {
+			t.Fatalf("expected git to report checksum %s, got %s", checksum, indexed)
+		}
+
+		verified := runGit(t, root, "verify-pack", "-v", packPath)
+		for _, hash := range objects {
+			if !strings.Contains(verified, hash) {
+				t.Fatalf("expected the pack to contain %s, got:\n%s", hash, verified)
+			}
+		}
+	})
+
+	t.Run("Delta-compresses similar blobs", func(t *testing.T) {
+		root := t.TempDir()
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		var lines []string
+		for i := 0; i < 500; i++ {
+			lines = append(lines, fmt.Sprintf("line %d of a file that keeps changing", i))
+		}
+
+		var objects []git.PackObject
+		for version := 0; version < 3; version++ {
+			lines[version*100] = fmt.Sprintf("edited in version %d", version)
+			hash, err := repository.WriteObject("blob", []byte(strings.Join(lines, "\n")))
+			if err != nil {
+				t.Fatalf("error writing blob: %v", err)
+			}
+			objects = append(objects, git.PackObject{Hash: hash, Path: "file.txt"})
+		}
+
+		var hashes []string
+		for _, object := range objects {
+			hashes = append(hashes, object.Hash)
+		}
+
+		var full, delta bytes.Buffer
+		_, err = repository.WritePack(&full, hashes)
+		if err != nil {
+			t.Fatalf("error writing pack: %v", err)
+		}
+
+		checksum, err := repository.WriteDeltaPack(&delta, objects)
+		if err != nil {
+			t.Fatalf("error writing delta pack: %v", err)
+		}
+
+		if delta.Len() >= full.Len() {
+			t.Fatalf("expected the delta pack to be smaller than %d bytes, got %d", full.Len(), delta.Len())
+		}
+
+		packPath := path.Join(t.TempDir(), "delta.pack")
+		err = os.WriteFile(packPath, delta.Bytes(), 0644)
+		if err != nil {
+			t.Fatalf("error writing pack: %v", err)
+		}
+
+		indexed := runGit(t, root, "index-pack", packPath)
+		if indexed != checksum {
+			t.Fatalf("expected git to report checksum %s, got %s", checksum, indexed)
+		}
+
+		verified := runGit(t, root, "verify-pack", "-v", packPath)
+		if !strings.Contains(verified, "chain length = 2: 1 object") {
+			t.Fatalf("expected a delta chain in the pack, got:\n%s", verified)
+		}
+
+		clone := t.TempDir()
+		runGit(t, clone, "init", "-q")
+		packFile, err := os.Open(packPath)
+		if err != nil {
+			t.Fatalf("error opening pack: %v", err)
+		}
+		defer packFile.Close()
+
+		cmd := exec.Command("git", "unpack-objects", "-q")
+		cmd.Dir = clone
+		cmd.Stdin = packFile
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("error unpacking: %v: %s", err, out)
+		}
+
+		for _, object := range objects {
+			expected, err := repository.CatFile(object.Hash)
+			if err != nil {
+				t.Fatalf("error reading object: %v", err)
+			}
+
+			contents := runGit(t, clone, "cat-file", "-p", object.Hash)
+			if contents != strings.TrimSpace(expected) {
+				t.Fatalf("expected the unpacked blob %s to match", object.Hash)
+			}
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+func (r *Repository) PackRefs(all bool) error {
+	return r.storage.PackRefs(all)
+}
+
+// PackRefs moves loose refs into packed-refs and deletes the loose files. Only
+// tags are packed unless all is set, like git. Symbolic refs stay loose.
+func (s *fileStorage) PackRefs(all bool) error {
+	packed, err := s.readPackedRefs()
+	if err != nil {
+		return err
+	}
+
+	names, err := s.listLooseRefs()
+	if err != nil {
+		return err
+	}
+
+	var moved []string
+	for _, name := range names {
+		if !all && !strings.HasPrefix(name, "refs/tags/") {
+			continue
+		}
+
+		value, err := s.readLooseRef(name)
+		if err != nil {
+			return err
+		}
+		if !isHash(value) {
+			continue
+		}
+
+		packed[name] = value
+		moved = append(moved, name)
+	}
+
+	err = s.writePackedRefs(packed)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range moved {
+		err = os.Remove(s.path(name))
+		if err != nil {
+			return fmt.Errorf("failed to remove loose ref %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *fileStorage) packedRefsPath() string {
+	return s.path("packed-refs")
+}
+
+// readPackedRefs maps ref names to hashes. Peeled "^" lines are skipped since
+// refs are resolved to the objects they name, not peeled.
+func (s *fileStorage) readPackedRefs() (map[string]string, error) {
+	refs := map[string]string{}
+
+	contents, err := os.ReadFile(s.packedRefsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return refs, nil
+		}
+
+		return nil, fmt.Errorf("failed to read packed-refs: %w", err)
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+
+		hash, name, found := strings.Cut(line, " ")
+		if !found || !isHash(hash) {
+			return nil, fmt.Errorf("malformed packed-refs line: %q", line)
+		}
+
+		refs[name] = hash
+	}
+
+	return refs, nil
+}
+
+func (s *fileStorage) writePackedRefs(refs map[string]string) error {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("# pack-refs with: sorted \n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s %s\n", refs[name], name)
+	}
+
+	tmpFile, err := os.CreateTemp(s.gitDir, "packed-refs.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create the file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.WriteString(sb.String())
+	if err == nil {
+		err = tmpFile.Chmod(0644)
+	}
+	if err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write packed-refs: %w", err)
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close packed-refs: %w", err)
+	}
+
+	err = os.Rename(tmpFile.Name(), s.packedRefsPath())
+	if err != nil {
+		return fmt.Errorf("failed to move packed-refs into place: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestPackRefs(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "initial")
+	runGit(t, root, "branch", "feature")
+	runGit(t, root, "tag", "v1")
+	runGit(t, root, "tag", "-a", "-m", "annotated", "v2")
+
+	expected := runGit(t, root, "show-ref")
+	repository := git.NewRepository(root)
+
+	loose := func(name string) bool {
+		_, err := os.Stat(path.Join(root, ".git", name))
+		return err == nil
+	}
+
+	t.Run("Packs only tags by default", func(t *testing.T) {
+		err := repository.PackRefs(false)
+		if err != nil {
+			t.Fatalf("error packing refs: %v", err)
+		}
+
+		if loose("refs/tags/v1") || loose("refs/tags/v2") {
+			t.Fatalf("expected the loose tags to be removed")
+		}
+		if !loose("refs/heads/main") || !loose("refs/heads/feature") {
+			t.Fatalf("expected the branches to stay loose")
+		}
+
+		if refs := runGit(t, root, "show-ref"); refs != expected {
+			t.Fatalf("expected git to see %q, got %q", expected, refs)
+		}
+
+		tag, err := repository.RevParse("v2")
+		if err != nil {
+			t.Fatalf("error resolving tag: %v", err)
+		}
+		if want := runGit(t, root, "rev-parse", "v2"); tag != want {
+			t.Fatalf("expected %s, got %s", want, tag)
+		}
+	})
+
+	t.Run("Packs branches with all", func(t *testing.T) {
+		err := repository.PackRefs(true)
+		if err != nil {
+			t.Fatalf("error packing refs: %v", err)
+		}
+
+		if loose("refs/heads/main") || loose("refs/heads/feature") {
+			t.Fatalf("expected the loose branches to be removed")
+		}
+
+		if refs := runGit(t, root, "show-ref"); refs != expected {
+			t.Fatalf("expected git to see %q, got %q", expected, refs)
+		}
+
+		head, err := repository.RevParse("HEAD")
+		if err != nil {
+			t.Fatalf("error resolving HEAD: %v", err)
+		}
+		if want := runGit(t, root, "rev-parse", "HEAD"); head != want {
+			t.Fatalf("expected %s, got %s", want, head)
+		}
+
+		refs, err := repository.Refs()
+		if err != nil {
+			t.Fatalf("error listing refs: %v", err)
+		}
+
+		var names []string
+		for _, ref := range refs {
+			names = append(names, ref.Name)
+		}
+		if want := []string{"refs/heads/feature", "refs/heads/main", "refs/tags/v1", "refs/tags/v2"}; !reflect.DeepEqual(names, want) {
+			t.Fatalf("expected %v, got %v", want, names)
+		}
+	})
+
+	t.Run("Prefers a loose ref over a packed one", func(t *testing.T) {
+		runGit(t, root, "commit", "-q", "--allow-empty", "-m", "second")
+		if !loose("refs/heads/main") {
+			t.Fatalf("expected git to write main as a loose ref")
+		}
+
+		main, err := repository.RevParse("main")
+		if err != nil {
+			t.Fatalf("error resolving main: %v", err)
+		}
+		if want := runGit(t, root, "rev-parse", "main"); main != want {
+			t.Fatalf("expected %s, got %s", want, main)
+		}
+	})
+
+	t.Run("Missing refs are still reported", func(t *testing.T) {
+		_, err := repository.RevParse("missing")
+		if !errors.Is(err, git.ErrRefNotFound) {
+			t.Fatalf("expected error %v, got %v", git.ErrRefNotFound, err)
+		}
+	})
+}
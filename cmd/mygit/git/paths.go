@@ -0,0 +1,116 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ErrAmbiguousHash = Error("ambiguous hash")
+	ErrNoObjectPath  = Error("storage does not keep objects in files")
+)
+
+// absPath resolves p against the current directory once, so a repository
+// opened with a relative root keeps working after the process changes directory.
+func absPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+
+	return abs
+}
+
+func (r *Repository) gitDir() string {
+	if r.bare {
+		return r.root
+	}
+
+	return filepath.Join(r.root, ".git")
+}
+
+// objectsDir honors GIT_OBJECT_DIRECTORY, which moves the object store out of
+// the git directory.
+func (r *Repository) objectsDir() string {
+	if dir := os.Getenv("GIT_OBJECT_DIRECTORY"); dir != "" {
+		return absPath(dir)
+	}
+
+	return filepath.Join(r.gitDir(), "objects")
+}
+
+// alternateObjectsDirs lists the extra object stores named in
+// GIT_ALTERNATE_OBJECT_DIRECTORIES, which are searched when reading objects.
+func (r *Repository) alternateObjectsDirs() []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv("GIT_ALTERNATE_OBJECT_DIRECTORIES")) {
+		if dir != "" {
+			dirs = append(dirs, absPath(dir))
+		}
+	}
+
+	return dirs
+}
+
+func (r *Repository) configPath() string {
+	return filepath.Join(r.gitDir(), "config")
+}
+
+func (r *Repository) infoExcludePath() string {
+	return filepath.Join(r.gitDir(), "info", "exclude")
+}
+
+// ObjectPath returns where the loose object named by hash, which may be
+// abbreviated to as few as 4 characters, is stored on disk. Only repositories
+// kept in files have one.
+func (r *Repository) ObjectPath(hash string) (string, error) {
+	storage, ok := r.storage.(*fileStorage)
+	if !ok {
+		return "", ErrNoObjectPath
+	}
+
+	full, err := r.expandHash(hash)
+	if err != nil {
+		return "", err
+	}
+
+	objectPath, _ := storage.findObject(full)
+	return objectPath, nil
+}
+
+func (r *Repository) expandHash(prefix string) (string, error) {
+	if isHash(prefix) {
+		if !r.hasObject(prefix) {
+			return "", fmt.Errorf("%w: %s", ErrObjectNotFound, prefix)
+		}
+
+		return prefix, nil
+	}
+
+	if len(prefix) < 4 || len(prefix) > 40 || !isHex(prefix) {
+		return "", fmt.Errorf("%w expected 4 to 40 hexadecimal characters, got: %s", ErrInvalidHash, prefix)
+	}
+
+	hashes, err := r.ListObjects()
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, hash := range hashes {
+		if strings.HasPrefix(hash, prefix) {
+			matches = append(matches, hash)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrObjectNotFound, prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s matches %d objects", ErrAmbiguousHash, prefix, len(matches))
+	}
+}
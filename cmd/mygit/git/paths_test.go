@@ -0,0 +1,83 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository Repository
+		gitDir     string
+	}{
+		{name: "Non-bare", repository: NewRepository("/work/repo"), gitDir: absPath(filepath.Join("/work", "repo", ".git"))},
+		{name: "Bare", repository: NewBareRepository("/srv/repo.git"), gitDir: absPath(filepath.Join("/srv", "repo.git"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := map[string]struct{ got, expected string }{
+				"gitDir":     {tt.repository.gitDir(), tt.gitDir},
+				"objectsDir": {tt.repository.objectsDir(), filepath.Join(tt.gitDir, "objects")},
+				"configPath": {tt.repository.configPath(), filepath.Join(tt.gitDir, "config")},
+			}
+			for name, p := range paths {
+				if p.got != p.expected {
+					t.Fatalf("expected %s to be %s, got %s", name, p.expected, p.got)
+				}
+			}
+		})
+	}
+
+	t.Run("Bare repositories initialize without a .git directory", func(t *testing.T) {
+		root := t.TempDir()
+		repository := NewBareRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		blobHash, err := repository.WriteObject("blob", []byte("bare"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		_, body, err := repository.readObject(blobHash)
+		if err != nil {
+			t.Fatalf("error reading object: %v", err)
+		}
+
+		if string(body) != "bare" {
+			t.Fatalf("expected bare, got %s", body)
+		}
+
+		objectPath, err := repository.ObjectPath(blobHash)
+		if err != nil {
+			t.Fatalf("error resolving object path: %v", err)
+		}
+
+		if objectPath != filepath.Join(root, "objects", blobHash[:2], blobHash[2:]) {
+			t.Fatalf("expected the object to be stored directly under %s", root)
+		}
+	})
+
+	t.Run("Joins a single tree entry name", func(t *testing.T) {
+		base := filepath.Join("work", "checkout")
+		joined, err := safeJoin(base, "file.go")
+		if err != nil {
+			t.Fatalf("error joining path: %v", err)
+		}
+
+		expected := filepath.Join("work", "checkout", "file.go")
+		if joined != expected {
+			t.Fatalf("expected %s, got %s", expected, joined)
+		}
+
+		for _, name := range []string{"src/file.go", "src" + string(filepath.Separator) + ".."} {
+			_, err = safeJoin(base, name)
+			if err == nil {
+				t.Fatalf("expected error joining %q, got nil", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// reachableObject is an object met while walking from a set of roots. A
+// missing object is referenced but not stored: its Type is the one implied by
+// whatever referenced it, and nothing below it is visited.
+type reachableObject struct {
+	Hash    string
+	Type    string
+	Size    int64
+	Missing bool
+}
+
+// MissingObjects walks everything reachable from roots and returns the sorted
+// hashes that are referenced but have no object in the store. Unlike a check
+// of stored objects, it finds the holes a partial fetch or a lost file leaves.
+func (r *Repository) MissingObjects(roots []string) ([]string, error) {
+	var missing []string
+	err := r.walkObjects(roots, func(object reachableObject) error {
+		if object.Missing {
+			missing = append(missing, object.Hash)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// ReachableSize counts the objects reachable from roots and sums the sizes
+// their headers declare, before compression. A missing object is an error.
+func (r *Repository) ReachableSize(roots []string) (int, int64, error) {
+	var count int
+	var total int64
+	err := r.walkObjects(roots, func(object reachableObject) error {
+		if object.Missing {
+			return fmt.Errorf("%w: %s", ErrObjectNotFound, object.Hash)
+		}
+
+		count++
+		total += object.Size
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, total, nil
+}
+
+// walkObjects visits every object reachable from roots once: commits lead to
+// their tree and parents, trees to their entries and tags to their target.
+// Gitlinks are skipped, as the commits they name live in another repository.
+func (r *Repository) walkObjects(roots []string, visit func(reachableObject) error) error {
+	type pending struct {
+		hash string
+		typ  string
+	}
+
+	var stack []pending
+	for _, root := range roots {
+		hash, err := r.RevParse(root)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", root, err)
+		}
+
+		stack = append(stack, pending{hash: hash})
+	}
+
+	seen := map[string]bool{}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[next.hash] {
+			continue
+		}
+		seen[next.hash] = true
+
+		if !r.hasObject(next.hash) {
+			err := visit(reachableObject{Hash: next.hash, Type: next.typ, Missing: true})
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		typ, size, err := r.ReadObjectHeader(next.hash)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", next.hash, err)
+		}
+
+		err = visit(reachableObject{Hash: next.hash, Type: typ, Size: size})
+		if err != nil {
+			return err
+		}
+
+		/*
+			Blobs lead nowhere, so only the other types are read in full.
+		*/
+		if typ == "blob" {
+			continue
+		}
+
+		_, body, err := r.readObject(next.hash)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", next.hash, err)
+		}
+
+		switch typ {
+		case "commit":
+			commit, err := parseCommit(next.hash, body)
+			if err != nil {
+				return err
+			}
+
+			stack = append(stack, pending{hash: commit.Tree, typ: "tree"})
+			for _, parent := range commit.Parents {
+				stack = append(stack, pending{hash: parent, typ: "commit"})
+			}
+
+		case "tree":
+			err = parseTreeEntries(bufio.NewReader(bytes.NewReader(body)), func(entry TreeEntry) error {
+				if entry.Mode != "160000" {
+					stack = append(stack, pending{hash: entry.Hash, typ: entry.Type()})
+				}
+
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to parse tree %s: %w", next.hash, err)
+			}
+
+		case "tag":
+			target, targetType, err := parseTagTarget(body)
+			if err != nil {
+				return fmt.Errorf("failed to parse tag %s: %w", next.hash, err)
+			}
+
+			stack = append(stack, pending{hash: target, typ: targetType})
+		}
+	}
+
+	return nil
+}
+
+// parseTagTarget returns the object a tag points at and its type, read from
+// the tag's object and type headers.
+func parseTagTarget(body []byte) (string, string, error) {
+	var target, targetType string
+	for _, line := range strings.Split(string(body), "\n") {
+		if line == "" {
+			break
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			target = value
+		case "type":
+			targetType = value
+		}
+	}
+
+	err := ValidateHash(target)
+	if err != nil {
+		return "", "", err
+	}
+
+	return target, targetType, nil
+}
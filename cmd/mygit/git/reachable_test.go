@@ -0,0 +1,114 @@
+package git_test
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestMissingObjects(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+
+	for name, contents := range map[string]string{"kept.txt": "kept\n", "dir/lost.txt": "lost\n"} {
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+	runGit(t, root, "tag", "-a", "-m", "release", "v1")
+
+	repository := git.NewRepository(root)
+
+	t.Run("Reports nothing for a complete repository", func(t *testing.T) {
+		missing, err := repository.MissingObjects([]string{"main", "v1"})
+		if err != nil {
+			t.Fatalf("error finding missing objects: %v", err)
+		}
+
+		if len(missing) != 0 {
+			t.Fatalf("expected no missing objects, got %v", missing)
+		}
+	})
+
+	t.Run("Reports a deleted blob", func(t *testing.T) {
+		lost := runGit(t, root, "rev-parse", "HEAD:dir/lost.txt")
+		objectPath, err := repository.ObjectPath(lost)
+		if err != nil {
+			t.Fatalf("error locating object: %v", err)
+		}
+
+		err = os.Remove(objectPath)
+		if err != nil {
+			t.Fatalf("error removing object: %v", err)
+		}
+
+		missing, err := repository.MissingObjects([]string{"v1"})
+		if err != nil {
+			t.Fatalf("error finding missing objects: %v", err)
+		}
+
+		if !reflect.DeepEqual(missing, []string{lost}) {
+			t.Fatalf("expected %s to be missing, got %v", lost, missing)
+		}
+	})
+}
+
+func TestReachableSize(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+
+	err := os.WriteFile(path.Join(root, "a.txt"), []byte("shared\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "first")
+
+	err = os.WriteFile(path.Join(root, "b.txt"), []byte("shared\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "second")
+
+	/*
+		Both files share one blob, so git lists 5 objects: 2 commits, 2 trees and the blob.
+	*/
+	lines := strings.Split(runGit(t, root, "rev-list", "--objects", "main"), "\n")
+	var expectedTotal int64
+	for _, line := range lines {
+		hash, _, _ := strings.Cut(line, " ")
+		size, err := strconv.ParseInt(runGit(t, root, "cat-file", "-s", hash), 10, 64)
+		if err != nil {
+			t.Fatalf("error parsing size: %v", err)
+		}
+		expectedTotal += size
+	}
+
+	repository := git.NewRepository(root)
+	count, total, err := repository.ReachableSize([]string{"main"})
+	if err != nil {
+		t.Fatalf("error sizing reachable objects: %v", err)
+	}
+
+	if count != 5 || count != len(lines) {
+		t.Fatalf("expected 5 objects, got %d", count)
+	}
+
+	if total != expectedTotal {
+		t.Fatalf("expected %d bytes, got %d", expectedTotal, total)
+	}
+}
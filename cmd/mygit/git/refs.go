@@ -0,0 +1,119 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	ErrRefNotFound              = Error("ref not found")
+	ErrTooManySymbolicRefLevels = Error("too many levels of symbolic refs")
+	ErrInvalidRefName           = Error("invalid ref name")
+)
+
+// Git gives up on symbolic refs nested deeper than this, which also catches cycles.
+const maxSymbolicRefDepth = 5
+
+type Ref struct {
+	Name string
+	Hash string
+}
+
+// Refs returns every ref under refs/ with the hash it resolves to, sorted by name.
+func (r *Repository) Refs() ([]Ref, error) {
+	names, err := r.storage.ListRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]Ref, 0, len(names))
+	for _, name := range names {
+		hash, err := r.resolveRef(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+
+		refs = append(refs, Ref{Name: name, Hash: hash})
+	}
+
+	return refs, nil
+}
+
+func (r *Repository) resolveRef(name string) (string, error) {
+	if name == "HEAD" {
+		return r.ResolveHead()
+	}
+
+	return r.resolveRefDepth(name, 0)
+}
+
+func (r *Repository) resolveRefDepth(name string, depth int) (string, error) {
+	if isHash(name) {
+		return name, nil
+	}
+
+	/*
+		Ref names become paths under .git, so one like ../../etc/passwd must never reach storage.
+	*/
+	if !validRefName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRefName, name)
+	}
+
+	candidates := []string{name}
+	if name != "HEAD" && !strings.HasPrefix(name, "refs/") {
+		candidates = []string{
+			path.Join("refs", name),
+			path.Join("refs", "tags", name),
+			path.Join("refs", "heads", name),
+			path.Join("refs", "remotes", name),
+		}
+	}
+
+	for _, candidate := range candidates {
+		value, err := r.storage.ReadRef(candidate)
+		if err != nil {
+			if errors.Is(err, ErrRefNotFound) {
+				continue
+			}
+
+			return "", err
+		}
+
+		if strings.HasPrefix(value, "ref: ") {
+			if depth >= maxSymbolicRefDepth {
+				return "", fmt.Errorf("%w: %s", ErrTooManySymbolicRefLevels, name)
+			}
+
+			return r.resolveRefDepth(strings.TrimPrefix(value, "ref: "), depth+1)
+		}
+
+		if !isHash(value) {
+			return "", fmt.Errorf("ref %s does not contain a valid hash", candidate)
+		}
+
+		return value, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+}
+
+// ValidateHash checks that hash is a full object name: 40 lowercase hex
+// characters. Only SHA-1 repositories are supported, so 64-character SHA-256
+// names are rejected as well.
+func ValidateHash(hash string) error {
+	if len(hash) != 40 {
+		return fmt.Errorf("%w expected 40 characters, got: %d", ErrInvalidHash, len(hash))
+	}
+
+	if !isHex(hash) {
+		return fmt.Errorf("%w expected hexadecimal characters, got: %s", ErrInvalidHash, hash)
+	}
+
+	return nil
+}
+
+func isHash(s string) bool {
+	return ValidateHash(s) == nil
+}
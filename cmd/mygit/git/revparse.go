@@ -0,0 +1,158 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	ErrPathNotFound = Error("path not found")
+	ErrNotATree     = Error("not a tree")
+)
+
+func (r *Repository) RevParse(rev string) (string, error) {
+	treeish, treePath, hasPath := strings.Cut(rev, ":")
+	if !hasPath {
+		hash, err := r.resolveRef(rev)
+		if errors.Is(err, ErrRefNotFound) && isHex(rev) {
+			return "", ValidateHash(rev)
+		}
+
+		return hash, err
+	}
+
+	if treeish == "" {
+		treeish = "HEAD"
+	}
+
+	hash, _, err := r.ResolvePath(treeish, treePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+
+	return hash, nil
+}
+
+func (r *Repository) ResolvePath(treeish, treePath string) (string, string, error) {
+	hash, err := r.resolveRef(treeish)
+	if err != nil {
+		return "", "", err
+	}
+
+	treeHash, err := r.peelToTree(hash)
+	if err != nil {
+		return "", "", err
+	}
+
+	ignoreCase, err := r.configBool("core.ignorecase")
+	if err != nil {
+		return "", "", err
+	}
+
+	entry, err := r.lookupTreePath(treeHash, treePath, ignoreCase)
+	if err != nil {
+		return "", "", err
+	}
+
+	return entry.Hash, entry.Mode, nil
+}
+
+// ResolveTree resolves treeish, a tree, a commit or a ref to either, to the
+// hash of a tree.
+func (r *Repository) ResolveTree(treeish string) (string, error) {
+	hash, err := r.RevParse(treeish)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", treeish, err)
+	}
+
+	treeHash, err := r.peelToTree(hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s to a tree: %w", treeish, err)
+	}
+
+	return treeHash, nil
+}
+
+func (r *Repository) peelToTree(hash string) (string, error) {
+	typ, body, err := r.readObject(hash)
+	if err != nil {
+		return "", err
+	}
+
+	switch typ {
+	case "tree":
+		return hash, nil
+
+	case "commit":
+		commit, err := parseCommit(hash, body)
+		if err != nil {
+			return "", err
+		}
+
+		return commit.Tree, nil
+
+	default:
+		return "", fmt.Errorf("expected %s to be a tree or a commit, got: %s", hash, typ)
+	}
+}
+
+// lookupTreePath finds the entry at treePath below a tree. With ignoreCase, as
+// when core.ignorecase is set, a component that has no exact match matches an
+// entry differing only in case.
+func (r *Repository) lookupTreePath(treeHash string, treePath string, ignoreCase bool) (TreeEntry, error) {
+	current := TreeEntry{Mode: "40000", Hash: treeHash}
+
+	walked := ""
+	for _, part := range strings.Split(strings.Trim(treePath, "/"), "/") {
+		if part == "" {
+			continue
+		}
+
+		if current.Mode != "40000" {
+			return TreeEntry{}, fmt.Errorf("%w: cannot descend into %s (mode %s)", ErrNotATree, walked, current.Mode)
+		}
+
+		entries, err := r.readTreeEntries(current.Hash)
+		if err != nil {
+			return TreeEntry{}, err
+		}
+
+		found := false
+		for _, entry := range entries {
+			if entry.Name == part {
+				current = entry
+				found = true
+				break
+			}
+		}
+		for i := 0; !found && ignoreCase && i < len(entries); i++ {
+			if strings.EqualFold(entries[i].Name, part) {
+				current = entries[i]
+				found = true
+			}
+		}
+
+		walked = path.Join(walked, part)
+		if !found {
+			return TreeEntry{}, fmt.Errorf("%w: %s (while resolving %s)", ErrPathNotFound, walked, treePath)
+		}
+	}
+
+	return current, nil
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,153 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestResolvePath(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+
+	err := os.MkdirAll(path.Join(root, "src", "pkg"), 0755)
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+	err = os.WriteFile(path.Join(root, "src", "pkg", "main.go"), []byte("package main\n"), 0755)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	repository := git.NewRepository(root)
+
+	t.Run("Resolves a nested blob", func(t *testing.T) {
+		hash, mode, err := repository.ResolvePath("HEAD", "src/pkg/main.go")
+		if err != nil {
+			t.Fatalf("error resolving path: %v", err)
+		}
+
+		expected := runGit(t, root, "rev-parse", "HEAD:src/pkg/main.go")
+		if hash != expected || mode != "100755" {
+			t.Fatalf("expected %s with mode 100755, got %s with mode %s", expected, hash, mode)
+		}
+	})
+
+	t.Run("Resolves a nested tree", func(t *testing.T) {
+		hash, mode, err := repository.ResolvePath("main", "src/pkg")
+		if err != nil {
+			t.Fatalf("error resolving path: %v", err)
+		}
+
+		expected := runGit(t, root, "rev-parse", "main:src/pkg")
+		if hash != expected || mode != "40000" {
+			t.Fatalf("expected %s with mode 40000, got %s with mode %s", expected, hash, mode)
+		}
+	})
+
+	t.Run("Fails on a missing component", func(t *testing.T) {
+		_, _, err := repository.ResolvePath("HEAD", "src/missing/main.go")
+		if !errors.Is(err, git.ErrPathNotFound) {
+			t.Fatalf("expected error %v, got %v", git.ErrPathNotFound, err)
+		}
+	})
+
+	t.Run("Fails when descending into a blob", func(t *testing.T) {
+		_, _, err := repository.ResolvePath("HEAD", "src/pkg/main.go/more")
+		if !errors.Is(err, git.ErrNotATree) {
+			t.Fatalf("expected error %v, got %v", git.ErrNotATree, err)
+		}
+	})
+
+	t.Run("Matches case-insensitively with core.ignorecase", func(t *testing.T) {
+		_, _, err := repository.ResolvePath("HEAD", "SRC/Pkg/Main.go")
+		if !errors.Is(err, git.ErrPathNotFound) {
+			t.Fatalf("expected error %v without core.ignorecase, got %v", git.ErrPathNotFound, err)
+		}
+
+		runGit(t, root, "config", "core.ignorecase", "true")
+		defer runGit(t, root, "config", "core.ignorecase", "false")
+
+		hash, mode, err := repository.ResolvePath("HEAD", "SRC/Pkg/Main.go")
+		if err != nil {
+			t.Fatalf("error resolving path: %v", err)
+		}
+
+		expected := runGit(t, root, "rev-parse", "HEAD:src/pkg/main.go")
+		if hash != expected || mode != "100755" {
+			t.Fatalf("expected %s with mode 100755, got %s with mode %s", expected, hash, mode)
+		}
+	})
+}
+
+func TestSymbolicRefChains(t *testing.T) {
+	storage := git.NewMemoryStorage()
+	repository := git.NewRepositoryWithStorage(t.TempDir(), storage)
+
+	commit, err := repository.WriteObject("commit", []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\ninitial\n"))
+	if err != nil {
+		t.Fatalf("error writing commit: %v", err)
+	}
+
+	refs := map[string]string{
+		"HEAD":             "ref: refs/heads/alias",
+		"refs/heads/alias": "ref: refs/heads/main",
+		"refs/heads/main":  commit,
+		"refs/heads/loop1": "ref: refs/heads/loop2",
+		"refs/heads/loop2": "ref: refs/heads/loop1",
+	}
+	for name, value := range refs {
+		err := storage.WriteRef(name, value)
+		if err != nil {
+			t.Fatalf("error writing ref: %v", err)
+		}
+	}
+
+	t.Run("Follows a chain of symbolic refs", func(t *testing.T) {
+		hash, err := repository.RevParse("HEAD")
+		if err != nil {
+			t.Fatalf("error resolving HEAD: %v", err)
+		}
+
+		if hash != commit {
+			t.Fatalf("expected %s, got %s", commit, hash)
+		}
+	})
+
+	t.Run("Stops at a cycle", func(t *testing.T) {
+		_, err := repository.RevParse("loop1")
+		if !errors.Is(err, git.ErrTooManySymbolicRefLevels) {
+			t.Fatalf("expected error %v, got %v", git.ErrTooManySymbolicRefLevels, err)
+		}
+	})
+}
+
+func TestRevParseRejectsEscapingRefs(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	err := os.WriteFile(path.Join(root, "secret.txt"), []byte("top secret\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	repository := git.NewRepository(root)
+	for _, rev := range []string{"../../secret.txt", "/etc/hostname", "heads/../../../secret.txt"} {
+		t.Run("Rejects "+rev, func(t *testing.T) {
+			_, err := repository.RevParse(rev)
+			if !errors.Is(err, git.ErrInvalidRefName) {
+				t.Fatalf("expected error %v, got %v", git.ErrInvalidRefName, err)
+			}
+
+			if strings.Contains(err.Error(), "top secret") {
+				t.Fatalf("expected the error not to leak file contents, got %v", err)
+			}
+		})
+	}
+}
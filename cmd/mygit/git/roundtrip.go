@@ -0,0 +1,39 @@
+package git
+
+import (
+	"fmt"
+	"os"
+)
+
+// RoundTripTree writes dir as a tree, extracts that tree into a temporary
+// directory and writes the copy again. It reports whether both writes produced
+// the same hash, as a self-test of the tree writer and reader.
+func (r *Repository) RoundTripTree(dir string) (bool, error) {
+	hash, err := r.WriteTree(dir)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.VerifyTree(hash)
+	if err != nil {
+		return false, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "mygit-roundtrip-")
+	if err != nil {
+		return false, fmt.Errorf("failed to create a temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = r.ExtractTree(hash, tmpDir)
+	if err != nil {
+		return false, err
+	}
+
+	rewritten, err := r.WriteTree(tmpDir)
+	if err != nil {
+		return false, err
+	}
+
+	return rewritten == hash, nil
+}
@@ -0,0 +1,170 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ErrIdentityUnknown = Error("identity unknown")
+
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("%s <%s> %d %s", s.Name, s.Email, s.When.Unix(), s.When.Format("-0700"))
+}
+
+func (r *Repository) defaultSignature(role string) (Signature, error) {
+	var prefix string
+	switch role {
+	case "author":
+		prefix = "GIT_AUTHOR_"
+	case "committer":
+		prefix = "GIT_COMMITTER_"
+	default:
+		return Signature{}, fmt.Errorf("unknown signature role: %s", role)
+	}
+
+	signature := Signature{
+		Name:  os.Getenv(prefix + "NAME"),
+		Email: os.Getenv(prefix + "EMAIL"),
+		When:  time.Now(),
+	}
+
+	if signature.Name == "" {
+		name, _, err := r.userConfigValue("user.name")
+		if err != nil {
+			return Signature{}, err
+		}
+		signature.Name = name
+	}
+
+	if signature.Email == "" {
+		email, _, err := r.userConfigValue("user.email")
+		if err != nil {
+			return Signature{}, err
+		}
+		signature.Email = email
+	}
+
+	if signature.Name == "" || signature.Email == "" {
+		return Signature{}, fmt.Errorf("%s %w: set %sNAME and %sEMAIL or user.name and user.email", role, ErrIdentityUnknown, prefix, prefix)
+	}
+
+	if date := os.Getenv(prefix + "DATE"); date != "" {
+		when, err := parseGitDate(date)
+		if err != nil {
+			return Signature{}, fmt.Errorf("invalid %sDATE: %w", prefix, err)
+		}
+		signature.When = when
+	}
+
+	return signature, nil
+}
+
+// UserIdentity returns the user.name and user.email set in the repository
+// config, or failing that the global config, dated now.
+func (r *Repository) UserIdentity() (Signature, error) {
+	name, _, err := r.userConfigValue("user.name")
+	if err != nil {
+		return Signature{}, err
+	}
+
+	email, _, err := r.userConfigValue("user.email")
+	if err != nil {
+		return Signature{}, err
+	}
+
+	if name == "" || email == "" {
+		return Signature{}, fmt.Errorf("%w: set user.name and user.email", ErrIdentityUnknown)
+	}
+
+	return Signature{Name: name, Email: email, When: time.Now()}, nil
+}
+
+// parseGitDate parses the date formats git accepts in GIT_AUTHOR_DATE and friends:
+// its internal `<unix-seconds> <+|-hhmm>` form, RFC 2822 and ISO 8601.
+func parseGitDate(date string) (time.Time, error) {
+	date = strings.TrimSpace(date)
+
+	seconds, offset, found := strings.Cut(strings.TrimPrefix(date, "@"), " ")
+	if unix, err := strconv.ParseInt(seconds, 10, 64); err == nil {
+		if !found {
+			return time.Unix(unix, 0).UTC(), nil
+		}
+
+		zone, err := parseTimezoneOffset(offset)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unrecognized date %q: %w", date, err)
+		}
+
+		return time.Unix(unix, 0).In(zone), nil
+	}
+
+	layouts := []string{
+		time.RFC1123Z,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04:05 -0700",
+		time.RFC3339,
+		"2006-01-02T15:04:05-0700",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05-07:00",
+	}
+	for _, layout := range layouts {
+		when, err := time.Parse(layout, date)
+		if err == nil {
+			return when, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", date)
+}
+
+func parseTimezoneOffset(offset string) (*time.Location, error) {
+	if len(offset) != 5 || (offset[0] != '+' && offset[0] != '-') {
+		return nil, fmt.Errorf("invalid timezone offset %q", offset)
+	}
+
+	hours, err := strconv.Atoi(offset[1:3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone offset %q: %w", offset, err)
+	}
+
+	minutes, err := strconv.Atoi(offset[3:5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone offset %q: %w", offset, err)
+	}
+
+	seconds := hours*3600 + minutes*60
+	if offset[0] == '-' {
+		seconds = -seconds
+	}
+
+	return time.FixedZone("", seconds), nil
+}
+
+// parseSignature parses `Name <email> <unix-seconds> <+|-hhmm>` as found in commits and tags.
+func parseSignature(value string) (Signature, error) {
+	start := strings.Index(value, "<")
+	end := strings.LastIndex(value, ">")
+	if start < 0 || end < start {
+		return Signature{}, fmt.Errorf("malformed signature %q", value)
+	}
+
+	when, err := parseGitDate(strings.TrimSpace(value[end+1:]))
+	if err != nil {
+		return Signature{}, err
+	}
+
+	return Signature{
+		Name:  strings.TrimSpace(value[:start]),
+		Email: value[start+1 : end],
+		When:  when,
+	}, nil
+}
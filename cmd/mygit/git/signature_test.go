@@ -0,0 +1,111 @@
+package git
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestDefaultSignature(t *testing.T) {
+	root := t.TempDir()
+	repository := NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	config := "[user]\n\tname = Config User\n\temail = config@example.com\n"
+	err = os.WriteFile(path.Join(root, ".git", "config"), []byte(config), 0644)
+	if err != nil {
+		t.Fatalf("error writing config: %v", err)
+	}
+
+	t.Run("Prefers the environment over the config", func(t *testing.T) {
+		t.Setenv("GIT_AUTHOR_NAME", "Env Author")
+		t.Setenv("GIT_AUTHOR_EMAIL", "author@example.com")
+		t.Setenv("GIT_AUTHOR_DATE", "1700000000 -0230")
+
+		signature, err := repository.defaultSignature("author")
+		if err != nil {
+			t.Fatalf("error resolving signature: %v", err)
+		}
+
+		expected := "author Env Author <author@example.com> 1700000000 -0230"
+		if header := "author " + signature.String(); header != expected {
+			t.Fatalf("expected %q, got %q", expected, header)
+		}
+	})
+
+	t.Run("Falls back to the user config", func(t *testing.T) {
+		t.Setenv("GIT_COMMITTER_NAME", "")
+		t.Setenv("GIT_COMMITTER_EMAIL", "")
+		t.Setenv("GIT_COMMITTER_DATE", "1700000000 +0100")
+
+		signature, err := repository.defaultSignature("committer")
+		if err != nil {
+			t.Fatalf("error resolving signature: %v", err)
+		}
+
+		expected := "committer Config User <config@example.com> 1700000000 +0100"
+		if header := "committer " + signature.String(); header != expected {
+			t.Fatalf("expected %q, got %q", expected, header)
+		}
+	})
+
+	t.Run("Mixes the environment and the config per field", func(t *testing.T) {
+		t.Setenv("GIT_COMMITTER_NAME", "Env Committer")
+		t.Setenv("GIT_COMMITTER_EMAIL", "")
+		t.Setenv("GIT_COMMITTER_DATE", "1700000000 +0000")
+
+		signature, err := repository.defaultSignature("committer")
+		if err != nil {
+			t.Fatalf("error resolving signature: %v", err)
+		}
+
+		expected := "Env Committer <config@example.com> 1700000000 +0000"
+		if signature.String() != expected {
+			t.Fatalf("expected %q, got %q", expected, signature.String())
+		}
+	})
+}
+
+func TestParseGitDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     string
+		unix     int64
+		offset   string
+		hasError bool
+	}{
+		{name: "Raw with a positive timezone", date: "1700000000 +0200", unix: 1700000000, offset: "+0200"},
+		{name: "Raw with a negative timezone", date: "1700000000 -0530", unix: 1700000000, offset: "-0530"},
+		{name: "Raw with an @ prefix", date: "@1700000000 +0000", unix: 1700000000, offset: "+0000"},
+		{name: "Raw without a timezone", date: "1700000000", unix: 1700000000, offset: "+0000"},
+		{name: "RFC 2822", date: "Tue, 14 Nov 2023 22:13:20 +0000", unix: 1700000000, offset: "+0000"},
+		{name: "RFC 2822 with a negative timezone", date: "Tue, 14 Nov 2023 17:13:20 -0500", unix: 1700000000, offset: "-0500"},
+		{name: "ISO 8601", date: "2023-11-14T22:13:20Z", unix: 1700000000, offset: "+0000"},
+		{name: "ISO 8601 with an offset", date: "2023-11-14T14:13:20-08:00", unix: 1700000000, offset: "-0800"},
+		{name: "ISO 8601 with a space", date: "2023-11-15 00:13:20 +0200", unix: 1700000000, offset: "+0200"},
+		{name: "Garbage", date: "yesterday-ish", hasError: true},
+		{name: "Raw with a malformed timezone", date: "1700000000 +2", hasError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			when, err := parseGitDate(tt.date)
+			if tt.hasError {
+				if err == nil {
+					t.Fatalf("expected error parsing %q, got %v", tt.date, when)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("error parsing %q: %v", tt.date, err)
+			}
+
+			if when.Unix() != tt.unix || when.Format("-0700") != tt.offset {
+				t.Fatalf("expected %d %s, got %d %s", tt.unix, tt.offset, when.Unix(), when.Format("-0700"))
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+const (
+	ErrNoLocalChanges = Error("no local changes to save")
+	ErrNoStash        = Error("no stash entries found")
+	ErrDirtyWorkTree  = Error("working tree has local changes")
+)
+
+const (
+	stashRef = "refs/stash"
+	zeroHash = "0000000000000000000000000000000000000000"
+)
+
+// StashSave records the working tree as a commit on top of HEAD, pushes it
+// onto refs/stash and resets the working tree to HEAD. Without an index there
+// is nothing to tell tracked and untracked files apart, so both are stashed,
+// but ignored files are left where they are.
+func (r *Repository) StashSave(message string) (string, error) {
+	if r.bare {
+		return "", fmt.Errorf("cannot stash in a bare repository")
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	if head.Hash == "" {
+		return "", fmt.Errorf("cannot stash on %s, it has no commits yet", head.Ref)
+	}
+
+	headCommit, err := r.ReadCommit(head.Hash)
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := r.writeWorkTree()
+	if err != nil {
+		return "", err
+	}
+	if tree == headCommit.Tree {
+		return "", ErrNoLocalChanges
+	}
+
+	branch := "(no branch)"
+	if head.Symbolic {
+		branch = strings.TrimPrefix(head.Ref, "refs/heads/")
+	}
+
+	if message == "" {
+		message = fmt.Sprintf("WIP on %s: %s %s", branch, shortHash(head.Hash), headCommit.Subject())
+	} else {
+		message = fmt.Sprintf("On %s: %s", branch, message)
+	}
+
+	stash, err := r.CommitTree(tree, []string{head.Hash}, message)
+	if err != nil {
+		return "", fmt.Errorf("failed to write the stash commit: %w", err)
+	}
+
+	entries, err := r.stashEntries()
+	if err != nil {
+		return "", err
+	}
+
+	previous := zeroHash
+	if len(entries) > 0 {
+		previous = entries[len(entries)-1].new
+	}
+
+	committer, err := r.defaultSignature("committer")
+	if err != nil {
+		return "", err
+	}
+
+	entries = append(entries, reflogEntry{old: previous, new: stash, committer: committer, message: message})
+	err = r.writeStashEntries(entries)
+	if err != nil {
+		return "", err
+	}
+
+	err = r.resetWorkTree(tree, headCommit.Tree)
+	if err != nil {
+		return "", fmt.Errorf("failed to reset the working tree: %w", err)
+	}
+
+	return stash, nil
+}
+
+// StashPop restores the working tree from the latest stash entry and drops it.
+// It refuses to run over local changes rather than merge them.
+func (r *Repository) StashPop() error {
+	entries, err := r.stashEntries()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return ErrNoStash
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	headTree := EmptyTreeHash(ObjectFormatSHA1)
+	if head.Hash != "" {
+		headTree, err = r.CommitTreeHash(head.Hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	tree, err := r.writeWorkTree()
+	if err != nil {
+		return err
+	}
+	if tree != headTree {
+		return ErrDirtyWorkTree
+	}
+
+	latest := entries[len(entries)-1]
+	stashTree, err := r.CommitTreeHash(latest.new)
+	if err != nil {
+		return err
+	}
+
+	err = r.resetWorkTree(headTree, stashTree)
+	if err != nil {
+		return fmt.Errorf("failed to restore the working tree: %w", err)
+	}
+
+	return r.writeStashEntries(entries[:len(entries)-1])
+}
+
+type reflogEntry struct {
+	old       string
+	new       string
+	committer Signature
+	message   string
+}
+
+func (e reflogEntry) String() string {
+	return fmt.Sprintf("%s %s %s\t%s\n", e.old, e.new, e.committer, e.message)
+}
+
+func (r *Repository) appendReflog(name string, entry reflogEntry) error {
+	contents, err := r.storage.ReadReflog(name)
+	if err != nil {
+		return err
+	}
+
+	return r.storage.WriteReflog(name, contents+entry.String())
+}
+
+func (r *Repository) stashEntries() ([]reflogEntry, error) {
+	contents, err := r.storage.ReadReflog(stashRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []reflogEntry
+	for _, line := range strings.Split(strings.TrimSuffix(contents, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+
+		header, message, _ := strings.Cut(line, "\t")
+		fields := strings.SplitN(header, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed reflog line for %s: %q", stashRef, line)
+		}
+
+		committer, err := parseSignature(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse reflog line for %s: %w", stashRef, err)
+		}
+
+		entries = append(entries, reflogEntry{old: fields[0], new: fields[1], committer: committer, message: message})
+	}
+
+	return entries, nil
+}
+
+func (r *Repository) writeStashEntries(entries []reflogEntry) error {
+	var contents strings.Builder
+	for _, entry := range entries {
+		contents.WriteString(entry.String())
+	}
+
+	err := r.storage.WriteReflog(stashRef, contents.String())
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return r.storage.DeleteRef(stashRef)
+	}
+
+	return r.storage.WriteRef(stashRef, entries[len(entries)-1].new)
+}
+
+// writeWorkTree writes the working tree as it would be after adding every file
+// the ignore rules let through.
+func (r *Repository) writeWorkTree() (string, error) {
+	ignore := newIgnoreMatcher(r.root, r.infoExcludePath())
+	return r.writeTree(r.root, nil, ignore, map[string]bool{})
+}
+
+// resetWorkTree swaps the paths recorded in from, which the working tree is
+// known to match, for the contents of to. Anything from does not record, like
+// ignored files and empty directories, is left alone.
+func (r *Repository) resetWorkTree(from string, to string) error {
+	err := r.removeTree(from, r.root)
+	if err != nil {
+		return err
+	}
+
+	return r.ExtractTree(to, r.root)
+}
+
+// removeTree deletes the files of tree from dir, and the directories that are
+// empty once they are gone.
+func (r *Repository) removeTree(tree string, dir string) error {
+	entries, err := r.readTreeEntries(tree)
+	if err != nil {
+		return fmt.Errorf("failed to read tree %s: %w", tree, err)
+	}
+
+	for _, entry := range entries {
+		target, err := safeJoin(dir, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		if entry.Mode == "40000" {
+			err = r.removeTree(entry.Hash, target)
+			if err != nil {
+				return err
+			}
+		}
+
+		if entry.Mode == "40000" || entry.Mode == "160000" {
+			left, err := os.ReadDir(target)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to read %s: %w", target, err)
+			}
+			if len(left) > 0 {
+				continue
+			}
+		}
+
+		err = os.Remove(target)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestStash(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "config", "user.name", "Test")
+	runGit(t, root, "config", "user.email", "test@example.com")
+
+	err := os.WriteFile(path.Join(root, "file.txt"), []byte("committed\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	repository := git.NewRepository(root)
+
+	t.Run("Refuses to stash a clean working tree", func(t *testing.T) {
+		_, err := repository.StashSave("")
+		if !errors.Is(err, git.ErrNoLocalChanges) {
+			t.Fatalf("expected error %v, got %v", git.ErrNoLocalChanges, err)
+		}
+	})
+
+	t.Run("Saves and pops local changes", func(t *testing.T) {
+		err := os.WriteFile(path.Join(root, "file.txt"), []byte("modified\n"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+
+		stash, err := repository.StashSave("work in progress")
+		if err != nil {
+			t.Fatalf("error saving stash: %v", err)
+		}
+
+		contents, err := os.ReadFile(path.Join(root, "file.txt"))
+		if err != nil {
+			t.Fatalf("error reading file: %v", err)
+		}
+		if string(contents) != "committed\n" {
+			t.Fatalf("expected the working tree to be reset, got %q", contents)
+		}
+
+		list := runGit(t, root, "stash", "list", "--format=%H %gs")
+		if list != stash+" On main: work in progress" {
+			t.Fatalf("expected git to list the stash, got %q", list)
+		}
+
+		err = repository.StashPop()
+		if err != nil {
+			t.Fatalf("error popping stash: %v", err)
+		}
+
+		contents, err = os.ReadFile(path.Join(root, "file.txt"))
+		if err != nil {
+			t.Fatalf("error reading file: %v", err)
+		}
+		if string(contents) != "modified\n" {
+			t.Fatalf("expected the stashed change to be restored, got %q", contents)
+		}
+
+		list = runGit(t, root, "stash", "list")
+		if list != "" {
+			t.Fatalf("expected the stash to be dropped, got %q", list)
+		}
+	})
+
+	t.Run("Refuses to pop over local changes", func(t *testing.T) {
+		_, err := repository.StashSave("")
+		if err != nil {
+			t.Fatalf("error saving stash: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, "other.txt"), []byte("new\n"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+
+		err = repository.StashPop()
+		if !errors.Is(err, git.ErrDirtyWorkTree) {
+			t.Fatalf("expected error %v, got %v", git.ErrDirtyWorkTree, err)
+		}
+	})
+}
+
+func TestStashLeavesUnrecordedPaths(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "config", "user.name", "Test")
+	runGit(t, root, "config", "user.email", "test@example.com")
+
+	files := map[string]string{".gitignore": "*.log\n", "file.txt": "committed\n"}
+	for name, contents := range files {
+		err := os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	err := os.Mkdir(path.Join(root, "empty"), 0755)
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+
+	changes := map[string]string{"debug.log": "ignored\n", "file.txt": "modified\n"}
+	for name, contents := range changes {
+		err := os.WriteFile(path.Join(root, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	assertKept := func(t *testing.T) {
+		t.Helper()
+		for _, name := range []string{"debug.log", "empty"} {
+			_, err := os.Stat(path.Join(root, name))
+			if err != nil {
+				t.Fatalf("expected %s to be left in place: %v", name, err)
+			}
+		}
+	}
+
+	repository := git.NewRepository(root)
+	_, err = repository.StashSave("")
+	if err != nil {
+		t.Fatalf("error saving stash: %v", err)
+	}
+	assertKept(t)
+
+	stashed := runGit(t, root, "ls-tree", "--name-only", "refs/stash")
+	if stashed != ".gitignore\nfile.txt" {
+		t.Fatalf("expected ignored files to stay out of the stash, got %q", stashed)
+	}
+
+	err = repository.StashPop()
+	if err != nil {
+		t.Fatalf("error popping stash: %v", err)
+	}
+	assertKept(t)
+
+	contents, err := os.ReadFile(path.Join(root, "file.txt"))
+	if err != nil {
+		t.Fatalf("error reading file: %v", err)
+	}
+	if string(contents) != "modified\n" {
+		t.Fatalf("expected the stashed change to be restored, got %q", contents)
+	}
+}
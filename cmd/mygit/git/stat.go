@@ -0,0 +1,235 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+const statWidth = 80
+
+// FileStat counts the lines a change adds and deletes. Binary files are not
+// counted by line; their sizes are recorded instead.
+type FileStat struct {
+	Path    string
+	Added   int
+	Deleted int
+	Binary  bool
+	OldSize int
+	NewSize int
+}
+
+// CommitStat returns per-file line counts for the changes a commit makes
+// relative to its first parent.
+func (r *Repository) CommitStat(commit string) ([]FileStat, error) {
+	changes, err := r.CommitChanges(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]FileStat, 0, len(changes))
+	for _, change := range changes {
+		oldContents, err := r.changeSide(change.OldMode, change.OldHash)
+		if err != nil {
+			return nil, err
+		}
+
+		newContents, err := r.changeSide(change.NewMode, change.NewHash)
+		if err != nil {
+			return nil, err
+		}
+
+		stat := FileStat{Path: change.Path, OldSize: len(oldContents), NewSize: len(newContents)}
+		if isBinary(oldContents) || isBinary(newContents) {
+			stat.Binary = true
+		} else {
+			stat.Added, stat.Deleted = countLineChanges(splitLines(oldContents), splitLines(newContents))
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// changeSide returns the contents of one side of a change. Gitlinks are
+// compared by the commit they point at, as git does.
+func (r *Repository) changeSide(mode, hash string) ([]byte, error) {
+	switch {
+	case hash == "":
+		return nil, nil
+	case mode == "160000":
+		return []byte("Subproject commit " + hash + "\n"), nil
+	}
+
+	_, contents, err := r.readObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", hash, err)
+	}
+
+	return contents, nil
+}
+
+// FormatStat renders stats like `git diff --stat`: a line per file with a
+// histogram of additions and deletions, scaled to fit 80 columns, followed by
+// a summary line.
+func FormatStat(stats []FileStat) string {
+	if len(stats) == 0 {
+		return ""
+	}
+
+	nameWidth, maxChange, hasBinary := 0, 0, false
+	for _, stat := range stats {
+		if len(stat.Path) > nameWidth {
+			nameWidth = len(stat.Path)
+		}
+		if stat.Binary {
+			hasBinary = true
+		} else if stat.Added+stat.Deleted > maxChange {
+			maxChange = stat.Added + stat.Deleted
+		}
+	}
+
+	numberWidth := len(fmt.Sprint(maxChange))
+	if hasBinary && numberWidth < len("Bin") {
+		numberWidth = len("Bin")
+	}
+
+	graphWidth := maxChange
+	if nameWidth+numberWidth+6+graphWidth > statWidth {
+		if graphWidth > statWidth*3/8-numberWidth-6 {
+			graphWidth = statWidth*3/8 - numberWidth - 6
+			if graphWidth < 6 {
+				graphWidth = 6
+			}
+		}
+
+		if nameWidth > statWidth-numberWidth-6-graphWidth {
+			nameWidth = statWidth - numberWidth - 6 - graphWidth
+		} else {
+			graphWidth = statWidth - numberWidth - 6 - nameWidth
+		}
+	}
+
+	var sb strings.Builder
+	insertions, deletions := 0, 0
+	for _, stat := range stats {
+		name := truncateStatName(stat.Path, nameWidth)
+		if stat.Binary {
+			fmt.Fprintf(&sb, " %-*s | %*s %d -> %d bytes\n", nameWidth, name, numberWidth, "Bin", stat.OldSize, stat.NewSize)
+			continue
+		}
+
+		insertions += stat.Added
+		deletions += stat.Deleted
+
+		added, deleted := stat.Added, stat.Deleted
+		if graphWidth <= maxChange {
+			total := scaleLinear(added+deleted, graphWidth, maxChange)
+			if total < 2 && added > 0 && deleted > 0 {
+				total = 2
+			}
+
+			if added < deleted {
+				added = scaleLinear(added, graphWidth, maxChange)
+				deleted = total - added
+			} else {
+				deleted = scaleLinear(deleted, graphWidth, maxChange)
+				added = total - deleted
+			}
+		}
+
+		fmt.Fprintf(&sb, " %-*s | %*d", nameWidth, name, numberWidth, stat.Added+stat.Deleted)
+		if added+deleted > 0 {
+			sb.WriteString(" " + strings.Repeat("+", added) + strings.Repeat("-", deleted))
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, " %d %s changed", len(stats), plural(len(stats), "file", "files"))
+	if insertions > 0 || deletions == 0 {
+		fmt.Fprintf(&sb, ", %d %s(+)", insertions, plural(insertions, "insertion", "insertions"))
+	}
+	if deletions > 0 || insertions == 0 {
+		fmt.Fprintf(&sb, ", %d %s(-)", deletions, plural(deletions, "deletion", "deletions"))
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+func scaleLinear(n, width, maxChange int) int {
+	if n == 0 {
+		return 0
+	}
+
+	return 1 + n*(width-1)/maxChange
+}
+
+// truncateStatName shortens a path that does not fit to "..." and its tail,
+// starting at a directory boundary when there is one.
+func truncateStatName(name string, width int) string {
+	if len(name) <= width {
+		return name
+	}
+
+	tail := name[len(name)-(width-3):]
+	if slash := strings.IndexByte(tail, '/'); slash >= 0 {
+		tail = tail[slash:]
+	}
+
+	return "..." + tail
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+
+	return plural
+}
+
+// splitLines keeps the line terminators, so a missing final newline counts as
+// a change to the last line.
+func splitLines(contents []byte) []string {
+	lines := strings.SplitAfter(string(contents), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// countLineChanges finds the length of the shortest edit script between two
+// line slices with Myers' algorithm and splits it into additions and deletions.
+func countLineChanges(a, b []string) (int, int) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*(n+m)+3)
+
+	for d := 0; d <= n+m; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				/*
+					Every edit is an addition or a deletion, and the deletions exceed the additions by n - m.
+				*/
+				return (d - n + m) / 2, (d + n - m) / 2
+			}
+		}
+	}
+
+	return m, n
+}
@@ -0,0 +1,606 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ErrObjectNotFound = Error("object not found")
+
+// Storage is where a Repository keeps its objects and refs. Objects are handed
+// over already encoded (zlib or raw), so a Storage only moves bytes around.
+// HEAD is a ref like any other, named "HEAD".
+type Storage interface {
+	Init() error
+	Destroy() error
+	/*
+		Exists reports whether the storage already holds a repository, whoever created it.
+	*/
+	Exists() bool
+
+	HasObject(hash string) bool
+	ReadObject(hash string) (io.ReadCloser, error)
+	WriteObject(hash string, data []byte) error
+	DeleteObject(hash string) error
+	ListObjects() ([]string, error)
+
+	ReadRef(name string) (string, error)
+	WriteRef(name string, value string) error
+	DeleteRef(name string) error
+	/*
+		ListRefs returns the sorted names of every ref under refs/.
+	*/
+	ListRefs() ([]string, error)
+	PackRefs(all bool) error
+
+	/*
+		Reflogs are handed over whole; ReadReflog returns an empty string when there is none.
+	*/
+	ReadReflog(name string) (string, error)
+	WriteReflog(name string, contents string) error
+}
+
+type fileStorage struct {
+	gitDir     string
+	objectsDir string
+	/*
+		Alternates are read-only object stores searched after objectsDir.
+	*/
+	alternates []string
+	bare       bool
+
+	/*
+		Writers of the same object share a shard, picked by the first byte of its hash.
+	*/
+	locks [256]sync.Mutex
+}
+
+func (s *fileStorage) lockObject(hash string) func() {
+	shard, _ := strconv.ParseUint(hash[:2], 16, 8)
+	s.locks[shard].Lock()
+	return s.locks[shard].Unlock
+}
+
+func (s *fileStorage) Init() error {
+	dirs := []string{
+		s.gitDir,
+		s.objectsDir,
+		s.path("refs"),
+	}
+	for _, dir := range dirs {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return fmt.Errorf("error creating directory %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *fileStorage) Exists() bool {
+	/*
+		A bare repository shares root with whatever else lives there, so look for its pieces instead.
+	*/
+	targets := []string{s.gitDir}
+	if s.bare {
+		targets = []string{s.objectsDir, s.path("HEAD")}
+	}
+
+	for _, target := range targets {
+		_, err := os.Stat(target)
+		if err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *fileStorage) Destroy() error {
+	/*
+		A bare repository lives directly in root, so only remove what Init created.
+	*/
+	targets := []string{s.gitDir}
+	if s.bare {
+		targets = []string{s.objectsDir, s.path("refs"), s.path("HEAD")}
+	}
+
+	for _, target := range targets {
+		err := os.RemoveAll(target)
+		if err != nil {
+			return fmt.Errorf("error cleaning up: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// path returns where name, a slash-separated path relative to the git
+// directory such as a ref name, is stored.
+func (s *fileStorage) path(name string) string {
+	return filepath.Join(s.gitDir, filepath.FromSlash(name))
+}
+
+// looseObjectPath returns where hash is stored as a loose object under objectsDir,
+// which may be the object store or one of its alternates.
+func looseObjectPath(objectsDir, hash string) string {
+	return filepath.Join(objectsDir, hash[:2], hash[2:])
+}
+
+// findObject returns the path of hash in the object store or the first
+// alternate holding it, or the path in the object store if none does.
+func (s *fileStorage) findObject(hash string) (string, bool) {
+	for _, dir := range append([]string{s.objectsDir}, s.alternates...) {
+		p := looseObjectPath(dir, hash)
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+
+	return looseObjectPath(s.objectsDir, hash), false
+}
+
+func (s *fileStorage) HasObject(hash string) bool {
+	_, found := s.findObject(hash)
+	return found
+}
+
+func (s *fileStorage) ReadObject(hash string) (io.ReadCloser, error) {
+	objectPath, _ := s.findObject(hash)
+	objectFile, err := os.Open(objectPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s does not exist", ErrObjectNotFound, objectPath)
+		}
+
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return objectFile, nil
+}
+
+// WriteObject writes to a temporary file and renames it into place, so readers
+// never see a partially written object.
+func (s *fileStorage) WriteObject(hash string, data []byte) error {
+	unlock := s.lockObject(hash)
+	defer unlock()
+
+	/*
+		Objects are immutable, so one that is already stored does not need to be written again.
+	*/
+	if s.HasObject(hash) {
+		return nil
+	}
+
+	/*
+		Like git, directories get 0777 and objects 0444, both minus the umask, which the kernel
+		applies when they are created.
+	*/
+	objectPath := looseObjectPath(s.objectsDir, hash)
+	err := os.MkdirAll(filepath.Dir(objectPath), 0777)
+	if err != nil {
+		return fmt.Errorf("failed to create the directory: %w", err)
+	}
+
+	tmpFile, err := createObjectFile(filepath.Dir(objectPath))
+	if err != nil {
+		return fmt.Errorf("failed to create the file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write the contents: %w", err)
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close the file: %w", err)
+	}
+
+	err = os.Rename(tmpFile.Name(), objectPath)
+	if err != nil {
+		return fmt.Errorf("failed to move the object into place: %w", err)
+	}
+
+	return nil
+}
+
+// createObjectFile creates a uniquely named, read-only temporary file in dir.
+// Unlike os.CreateTemp it sets the mode on creation, so the umask applies.
+func createObjectFile(dir string) (*os.File, error) {
+	for i := 0; ; i++ {
+		name := filepath.Join(dir, "tmp_obj_"+strconv.Itoa(os.Getpid())+"_"+strconv.FormatInt(time.Now().UnixNano()+int64(i), 36))
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0444)
+		if errors.Is(err, fs.ErrExist) && i < 100 {
+			continue
+		}
+
+		return file, err
+	}
+}
+
+// DeleteObject removes a loose object from the object store. Alternates are
+// never modified.
+func (s *fileStorage) DeleteObject(hash string) error {
+	unlock := s.lockObject(hash)
+	defer unlock()
+
+	err := os.Remove(looseObjectPath(s.objectsDir, hash))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove the object: %w", err)
+	}
+
+	return nil
+}
+
+func (s *fileStorage) ListObjects() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.objectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the objects directory: %w", err)
+	}
+
+	var hashes []string
+	for _, dirEntry := range dirEntries {
+		/*
+			This also skips the pack and info directories.
+		*/
+		if !dirEntry.IsDir() || len(dirEntry.Name()) != 2 || !isHex(dirEntry.Name()) {
+			continue
+		}
+
+		objectEntries, err := os.ReadDir(filepath.Join(s.objectsDir, dirEntry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the directory: %w", err)
+		}
+
+		for _, objectEntry := range objectEntries {
+			hash := dirEntry.Name() + objectEntry.Name()
+			if objectEntry.IsDir() || !isHash(hash) {
+				continue
+			}
+
+			hashes = append(hashes, hash)
+		}
+	}
+
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+func (s *fileStorage) ReadRef(name string) (string, error) {
+	value, err := s.readLooseRef(name)
+	if !errors.Is(err, ErrRefNotFound) {
+		return value, err
+	}
+
+	packed, err := s.readPackedRefs()
+	if err != nil {
+		return "", err
+	}
+
+	hash, ok := packed[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+	}
+
+	return hash, nil
+}
+
+func (s *fileStorage) readLooseRef(name string) (string, error) {
+	contents, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+		}
+
+		return "", fmt.Errorf("failed to read ref %s: %w", name, err)
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
+
+func (s *fileStorage) WriteRef(name string, value string) error {
+	refPath := s.path(name)
+	err := os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create the directory: %w", err)
+	}
+
+	err = os.WriteFile(refPath, []byte(value+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing to file %s: %w", refPath, err)
+	}
+
+	return nil
+}
+
+func (s *fileStorage) DeleteRef(name string) error {
+	err := os.Remove(s.path(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete ref %s: %w", name, err)
+	}
+
+	packed, err := s.readPackedRefs()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := packed[name]; !ok {
+		return nil
+	}
+
+	delete(packed, name)
+	return s.writePackedRefs(packed)
+}
+
+func (s *fileStorage) ListRefs() ([]string, error) {
+	names, err := s.listLooseRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	packed, err := s.readPackedRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	/*
+		A loose ref shadows a packed one with the same name.
+	*/
+	seen := map[string]bool{}
+	for _, name := range names {
+		seen[name] = true
+	}
+	for name := range packed {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *fileStorage) listLooseRefs() ([]string, error) {
+	var names []string
+	refsDir := s.path("refs")
+	err := filepath.WalkDir(refsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.gitDir, p)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *fileStorage) reflogPath(name string) string {
+	return s.path("logs/" + name)
+}
+
+func (s *fileStorage) ReadReflog(name string) (string, error) {
+	contents, err := os.ReadFile(s.reflogPath(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to read the reflog of %s: %w", name, err)
+	}
+
+	return string(contents), nil
+}
+
+func (s *fileStorage) WriteReflog(name string, contents string) error {
+	reflogPath := s.reflogPath(name)
+	if contents == "" {
+		err := os.Remove(reflogPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete the reflog of %s: %w", name, err)
+		}
+
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(reflogPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create the directory: %w", err)
+	}
+
+	err = os.WriteFile(reflogPath, []byte(contents), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing to file %s: %w", reflogPath, err)
+	}
+
+	return nil
+}
+
+type memoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+	refs    map[string]string
+	reflogs map[string]string
+}
+
+func NewMemoryStorage() Storage {
+	return &memoryStorage{objects: map[string][]byte{}, refs: map[string]string{}, reflogs: map[string]string{}}
+}
+
+func (s *memoryStorage) Init() error {
+	return nil
+}
+
+func (s *memoryStorage) Exists() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.refs["HEAD"]
+	return ok
+}
+
+func (s *memoryStorage) Destroy() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects = map[string][]byte{}
+	s.refs = map[string]string{}
+	s.reflogs = map[string]string{}
+	return nil
+}
+
+func (s *memoryStorage) HasObject(hash string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.objects[hash]
+	return ok
+}
+
+func (s *memoryStorage) ReadObject(hash string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.objects[hash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, hash)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStorage) WriteObject(hash string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[hash] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memoryStorage) DeleteObject(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, hash)
+	return nil
+}
+
+func (s *memoryStorage) ListObjects() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hashes := make([]string, 0, len(s.objects))
+	for hash := range s.objects {
+		hashes = append(hashes, hash)
+	}
+
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+func (s *memoryStorage) ReadRef(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.refs[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+	}
+
+	return value, nil
+}
+
+func (s *memoryStorage) WriteRef(name string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs[name] = value
+	return nil
+}
+
+func (s *memoryStorage) DeleteRef(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.refs, name)
+	return nil
+}
+
+func (s *memoryStorage) ListRefs() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for name := range s.refs {
+		if strings.HasPrefix(name, "refs/") {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// PackRefs does nothing: refs in memory have no loose form to consolidate.
+func (s *memoryStorage) PackRefs(all bool) error {
+	return nil
+}
+
+func (s *memoryStorage) ReadReflog(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.reflogs[name], nil
+}
+
+func (s *memoryStorage) WriteReflog(name string, contents string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if contents == "" {
+		delete(s.reflogs, name)
+		return nil
+	}
+
+	s.reflogs[name] = contents
+	return nil
+}
+
+// discardStorage accepts objects and forgets them, for callers that only want
+// the hashes. Its other methods must not be called.
+type discardStorage struct {
+	Storage
+}
+
+func (discardStorage) HasObject(hash string) bool {
+	return false
+}
+
+func (discardStorage) WriteObject(hash string, data []byte) error {
+	return nil
+}
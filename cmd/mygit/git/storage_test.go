@@ -0,0 +1,235 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestStorageBackends(t *testing.T) {
+	type backend func(t *testing.T) (git.Repository, func(name, value string) error)
+
+	backends := map[string]backend{
+		"filesystem": func(t *testing.T) (git.Repository, func(name, value string) error) {
+			root := t.TempDir()
+			writeRef := func(name, value string) error {
+				refPath := path.Join(root, ".git", name)
+				err := os.MkdirAll(path.Dir(refPath), 0755)
+				if err != nil {
+					return err
+				}
+
+				return os.WriteFile(refPath, []byte(value+"\n"), 0644)
+			}
+			return git.NewRepository(root), writeRef
+		},
+		"memory": func(t *testing.T) (git.Repository, func(name, value string) error) {
+			storage := git.NewMemoryStorage()
+			return git.NewRepositoryWithStorage(t.TempDir(), storage), storage.WriteRef
+		},
+	}
+
+	for name, newRepository := range backends {
+		t.Run(name, func(t *testing.T) {
+			repository, writeRef := newRepository(t)
+			_, err := repository.Init()
+			if err != nil {
+				t.Fatalf("error initializing repository: %v", err)
+			}
+
+			t.Run("Round-trips objects", func(t *testing.T) {
+				hash, err := repository.WriteObject("blob", []byte("test content\n"))
+				if err != nil {
+					t.Fatalf("error writing object: %v", err)
+				}
+
+				if hash != "d670460b4b4aece5915caf5c68d12f560a9fe3e4" {
+					t.Fatalf("expected the canonical blob hash, got %s", hash)
+				}
+
+				contents, err := repository.CatFile(hash)
+				if err != nil {
+					t.Fatalf("error reading object: %v", err)
+				}
+
+				if contents != "test content\n" {
+					t.Fatalf("expected test content, got %q", contents)
+				}
+			})
+
+			t.Run("Reports missing objects", func(t *testing.T) {
+				_, err := repository.CatFile("0000000000000000000000000000000000000000")
+				if !errors.Is(err, git.ErrObjectNotFound) {
+					t.Fatalf("expected error %v, got %v", git.ErrObjectNotFound, err)
+				}
+			})
+
+			t.Run("Lists objects", func(t *testing.T) {
+				other, err := repository.WriteObject("blob", []byte("other"))
+				if err != nil {
+					t.Fatalf("error writing object: %v", err)
+				}
+
+				hashes, err := repository.ListObjects()
+				if err != nil {
+					t.Fatalf("error listing objects: %v", err)
+				}
+
+				expected := []string{"d670460b4b4aece5915caf5c68d12f560a9fe3e4", other}
+				sort.Strings(expected)
+				if !reflect.DeepEqual(hashes, expected) {
+					t.Fatalf("expected %v, got %v", expected, hashes)
+				}
+			})
+
+			t.Run("Resolves refs through HEAD", func(t *testing.T) {
+				commit, err := repository.WriteObject("commit", []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\ninitial\n"))
+				if err != nil {
+					t.Fatalf("error writing commit: %v", err)
+				}
+
+				err = writeRef("refs/heads/master", commit)
+				if err != nil {
+					t.Fatalf("error writing ref: %v", err)
+				}
+
+				resolved, err := repository.RevParse("HEAD")
+				if err != nil {
+					t.Fatalf("error resolving HEAD: %v", err)
+				}
+
+				if resolved != commit {
+					t.Fatalf("expected HEAD to resolve to %s, got %s", commit, resolved)
+				}
+			})
+		})
+	}
+}
+
+func TestConcurrentWriteObject(t *testing.T) {
+	root := t.TempDir()
+	repository := git.NewRepository(root)
+	_, err := repository.Init()
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+
+	contents := []byte(strings.Repeat("written by many goroutines\n", 1000))
+
+	var wg sync.WaitGroup
+	hashes := make([]string, 50)
+	errs := make([]error, 50)
+	for i := range hashes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hashes[i], errs[i] = repository.WriteObject("blob", contents)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		if hashes[i] != hashes[0] {
+			t.Fatalf("expected every write to return %s, got %s", hashes[0], hashes[i])
+		}
+	}
+
+	entries, err := os.ReadDir(path.Join(root, ".git", "objects", hashes[0][:2]))
+	if err != nil {
+		t.Fatalf("error reading objects directory: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != hashes[0][2:] {
+		t.Fatalf("expected only the object file to remain, got %v", entries)
+	}
+
+	stored, err := repository.CatFile(hashes[0])
+	if err != nil {
+		t.Fatalf("error reading object: %v", err)
+	}
+
+	if stored != string(contents) {
+		t.Fatalf("expected the stored object to match the contents")
+	}
+
+	runGit(t, root, "fsck", "--strict")
+}
+
+func TestObjectDirectoryOverrides(t *testing.T) {
+	t.Run("Writes objects to GIT_OBJECT_DIRECTORY", func(t *testing.T) {
+		root := t.TempDir()
+		objectsDir := t.TempDir()
+		t.Setenv("GIT_OBJECT_DIRECTORY", objectsDir)
+
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		hash, err := repository.WriteObject("blob", []byte("test content\n"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		_, err = os.Stat(path.Join(objectsDir, hash[:2], hash[2:]))
+		if err != nil {
+			t.Fatalf("expected the object in the custom directory: %v", err)
+		}
+
+		_, err = os.Stat(path.Join(root, ".git", "objects", hash[:2], hash[2:]))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected no object in .git/objects, got %v", err)
+		}
+
+		contents := runGit(t, root, "cat-file", "-p", hash)
+		if contents != "test content" {
+			t.Fatalf("expected git to read the object back, got %q", contents)
+		}
+	})
+
+	t.Run("Reads objects from GIT_ALTERNATE_OBJECT_DIRECTORIES", func(t *testing.T) {
+		other := t.TempDir()
+		runGit(t, other, "init")
+		hash := runGit(t, other, "hash-object", "-w", "--stdin")
+
+		root := t.TempDir()
+		t.Setenv("GIT_ALTERNATE_OBJECT_DIRECTORIES", path.Join(t.TempDir(), "missing")+string(os.PathListSeparator)+path.Join(other, ".git", "objects"))
+
+		repository := git.NewRepository(root)
+		_, err := repository.Init()
+		if err != nil {
+			t.Fatalf("error initializing repository: %v", err)
+		}
+
+		if !repository.ObjectExists(hash) {
+			t.Fatalf("expected %s to be found in the alternate", hash)
+		}
+
+		_, err = repository.CatFile(hash)
+		if err != nil {
+			t.Fatalf("error reading object from the alternate: %v", err)
+		}
+
+		written, err := repository.WriteObject("blob", []byte("local\n"))
+		if err != nil {
+			t.Fatalf("error writing object: %v", err)
+		}
+
+		_, err = os.Stat(path.Join(root, ".git", "objects", written[:2], written[2:]))
+		if err != nil {
+			t.Fatalf("expected new objects in the repository's own store: %v", err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+//go:build unix
+
+package git_test
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestObjectPermissions(t *testing.T) {
+	tests := []struct {
+		umask      int
+		dirMode    fs.FileMode
+		objectMode fs.FileMode
+	}{
+		{umask: 0022, dirMode: 0755, objectMode: 0444},
+		{umask: 0077, dirMode: 0700, objectMode: 0400},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Honors umask %04o", tt.umask), func(t *testing.T) {
+			previous := syscall.Umask(tt.umask)
+			defer syscall.Umask(previous)
+
+			root := t.TempDir()
+			repository := git.NewRepository(root)
+			_, err := repository.Init()
+			if err != nil {
+				t.Fatalf("error initializing repository: %v", err)
+			}
+
+			hash, err := repository.WriteObject("blob", []byte("test content\n"))
+			if err != nil {
+				t.Fatalf("error writing object: %v", err)
+			}
+
+			dirPath := path.Join(root, ".git", "objects", hash[:2])
+			info, err := os.Stat(dirPath)
+			if err != nil {
+				t.Fatalf("error stating directory: %v", err)
+			}
+
+			if info.Mode().Perm() != tt.dirMode {
+				t.Fatalf("expected directory mode %v, got %v", tt.dirMode, info.Mode().Perm())
+			}
+
+			info, err = os.Stat(path.Join(dirPath, hash[2:]))
+			if err != nil {
+				t.Fatalf("error stating object: %v", err)
+			}
+
+			if info.Mode().Perm() != tt.objectMode {
+				t.Fatalf("expected object mode %v, got %v", tt.objectMode, info.Mode().Perm())
+			}
+		})
+	}
+}
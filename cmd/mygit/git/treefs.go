@@ -0,0 +1,244 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (r *Repository) TreeFS(treeOrCommitHash string) (fs.FS, error) {
+	typ, body, err := r.readObject(treeOrCommitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case "tree":
+		return &treeFS{repository: r, root: treeOrCommitHash}, nil
+
+	case "commit":
+		commit, err := parseCommit(treeOrCommitHash, body)
+		if err != nil {
+			return nil, err
+		}
+
+		return &treeFS{repository: r, root: commit.Tree}, nil
+
+	default:
+		return nil, fmt.Errorf("expected a tree or a commit, got: %s", typ)
+	}
+}
+
+type treeFS struct {
+	repository *Repository
+	root       string
+}
+
+func (t *treeFS) Open(name string) (fs.File, error) {
+	entry, err := t.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := t.info(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if info.IsDir() {
+		entries, err := t.readDir(entry)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return &treeDir{info: info, entries: entries}, nil
+	}
+
+	_, contents, err := t.repository.readObject(entry.Hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &treeFile{info: info, Reader: bytes.NewReader(contents)}, nil
+}
+
+func (t *treeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, err := t.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Mode != "40000" && entry.Mode != "160000" {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+
+	entries, err := t.readDir(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return entries, nil
+}
+
+func (t *treeFS) Stat(name string) (fs.FileInfo, error) {
+	entry, err := t.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := t.info(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return info, nil
+}
+
+func (t *treeFS) lookup(op string, name string) (TreeEntry, error) {
+	if !fs.ValidPath(name) {
+		return TreeEntry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	current := TreeEntry{Mode: "40000", Name: ".", Hash: t.root}
+	if name == "." {
+		return current, nil
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if current.Mode != "40000" {
+			return TreeEntry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		entries, err := t.repository.readTreeEntries(current.Hash)
+		if err != nil {
+			return TreeEntry{}, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+
+		found := false
+		for _, entry := range entries {
+			if entry.Name == part {
+				current = entry
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return TreeEntry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+	}
+
+	return current, nil
+}
+
+func (t *treeFS) readDir(dir TreeEntry) ([]fs.DirEntry, error) {
+	/*
+		Gitlinks point into a submodule, so they show up as empty directories.
+	*/
+	if dir.Mode == "160000" {
+		return nil, nil
+	}
+
+	entries, err := t.repository.readTreeEntries(dir.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	dirEntries := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		info, err := t.info(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		dirEntries = append(dirEntries, fs.FileInfoToDirEntry(info))
+	}
+
+	sort.Slice(dirEntries, func(i, j int) bool {
+		return dirEntries[i].Name() < dirEntries[j].Name()
+	})
+
+	return dirEntries, nil
+}
+
+func (t *treeFS) info(entry TreeEntry) (*treeFileInfo, error) {
+	info := &treeFileInfo{name: entry.Name}
+
+	switch entry.Mode {
+	case "40000", "160000":
+		info.mode = fs.ModeDir | 0755
+		return info, nil
+	case "100755":
+		info.mode = 0755
+	case "120000":
+		info.mode = fs.ModeSymlink | 0777
+	default:
+		info.mode = 0644
+	}
+
+	_, size, rc, err := t.repository.OpenObject(entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	info.size = size
+	return info, nil
+}
+
+type treeFileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (i *treeFileInfo) Name() string       { return i.name }
+func (i *treeFileInfo) Size() int64        { return i.size }
+func (i *treeFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *treeFileInfo) ModTime() time.Time { return time.Time{} }
+func (i *treeFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *treeFileInfo) Sys() any           { return nil }
+
+type treeFile struct {
+	*bytes.Reader
+	info *treeFileInfo
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *treeFile) Close() error               { return nil }
+
+type treeDir struct {
+	info    *treeFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDir) Close() error               { return nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fmt.Errorf("is a directory")}
+}
+
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+
+	return remaining[:n], nil
+}
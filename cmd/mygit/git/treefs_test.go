@@ -0,0 +1,81 @@
+package git_test
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestTreeFS(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	files := map[string]os.FileMode{
+		"a.txt":               0644,
+		"run.sh":              0755,
+		"dir/b.txt":           0644,
+		"dir/nested/c.txt":    0644,
+		"dir.with.dots/d.txt": 0644,
+	}
+	for name, mode := range files {
+		err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(path.Join(root, name), []byte(name), mode)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+	commitHash := runGit(t, root, "rev-parse", "HEAD")
+
+	repository := git.NewRepository(root)
+	fsys, err := repository.TreeFS(commitHash)
+	if err != nil {
+		t.Fatalf("error opening tree: %v", err)
+	}
+
+	t.Run("Walks every committed file", func(t *testing.T) {
+		visited := map[string]fs.FileMode{}
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.IsDir() {
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				visited[p] = info.Mode()
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("error walking tree: %v", err)
+		}
+
+		if len(visited) != len(files) {
+			t.Fatalf("expected %d files, got %v", len(files), visited)
+		}
+		for name, mode := range files {
+			if visited[name] != mode {
+				t.Fatalf("expected %s with mode %v, got %v", name, mode, visited[name])
+			}
+		}
+	})
+
+	t.Run("Satisfies the fs.FS contract", func(t *testing.T) {
+		err := fstest.TestFS(fsys, "a.txt", "dir/nested/c.txt")
+		if err != nil {
+			t.Fatalf("error testing filesystem: %v", err)
+		}
+	})
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
 )
@@ -19,37 +23,203 @@ func main() {
 	flag.Parse()
 
 	err := run(*root, Command(flag.Arg(0)))
-	if err != nil {
+	var code exitCode
+	if err != nil && !errors.As(err, &code) {
 		fmt.Fprintf(os.Stderr, "Error: %s", err)
-		os.Exit(1)
 	}
+	os.Exit(exitStatus(err))
+}
+
+// exitStatus maps the result of run to the status the process exits with:
+// whatever an exitCode asks for, 1 for any other error and 0 on success.
+func exitStatus(err error) int {
+	var code exitCode
+	if errors.As(err, &code) {
+		return int(code)
+	}
+	if err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+// fileFS roots an fs.FS at the working directory and returns name relative to
+// it, so paths of any depth, with "./" or a trailing slash, all resolve the
+// same way. Paths outside the working directory are rooted at their parent.
+func fileFS(name string) (fs.FS, string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get the working directory: %w", err)
+	}
+
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return os.DirFS(filepath.Dir(abs)), filepath.Base(abs), nil
+	}
+
+	return os.DirFS(wd), filepath.ToSlash(rel), nil
+}
+
+// parseArgs parses flags up to the first "--" or "--end-of-options" and
+// returns the operands, so nothing after the separator is read as a flag.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	for i, arg := range args {
+		if arg != "--" && arg != "--end-of-options" {
+			continue
+		}
+
+		err := fs.Parse(args[:i])
+		if err != nil {
+			return nil, err
+		}
+
+		operands := append([]string{}, fs.Args()...)
+		return append(operands, args[i+1:]...), nil
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Args(), nil
+}
+
+// exitCode is returned by commands that report their result through the exit
+// status alone, like cat-file -e.
+type exitCode int
+
+func (e exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
 }
 
 type Command string
 
 const (
-	Init       Command = "init"
-	CatFile    Command = "cat-file"
-	HashObject Command = "hash-object"
-	LsTree     Command = "ls-tree"
-	WriteTree  Command = "write-tree"
+	Init        Command = "init"
+	CatFile     Command = "cat-file"
+	HashObject  Command = "hash-object"
+	LsTree      Command = "ls-tree"
+	WriteTree   Command = "write-tree"
+	Archive     Command = "archive"
+	CheckIgnore Command = "check-ignore"
+	Grep        Command = "grep"
+	Log         Command = "log"
+	ListObjects Command = "list-objects"
+	CommitTree  Command = "commit-tree"
+	Head        Command = "head"
+	Stash       Command = "stash"
+	Graph       Command = "graph"
+	ObjectPath  Command = "object-path"
+	PackRefs    Command = "pack-refs"
+	Config      Command = "config"
+	WhatChanged Command = "whatchanged"
+	Branch      Command = "branch"
+	HashTree    Command = "hash-tree"
+	Size        Command = "size"
 )
 
+// stringsFlag collects every occurrence of a flag that may be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func run(root string, command Command) error {
 	repository := git.NewRepository(root)
 	if command == Init {
-		_, err := repository.Init()
+		fs := flag.NewFlagSet("init", flag.ContinueOnError)
+		var fsBranch string
+		fs.StringVar(&fsBranch, "b", "", "name of the initial branch")
+		fs.StringVar(&fsBranch, "initial-branch", "", "name of the initial branch")
+		err := fs.Parse(flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		repository.DefaultBranch = fsBranch
+		_, err = repository.Init()
+		if errors.Is(err, git.ErrRepositoryAlreadyInitialized) {
+			return repository.Reinit()
+		}
 		return err
 	}
 
 	if command == CatFile {
 		fs := flag.NewFlagSet("cat-file", flag.ContinueOnError)
 		fsPrettyPrint := fs.String("p", "", "pretty print")
+		fsType := fs.String("t", "", "show the object type")
+		fsSize := fs.String("s", "", "show the object size")
+		fsExists := fs.String("e", "", "exit with zero status if the object exists and is valid")
+		fsStream := fs.String("stream", "", "copy the raw object body to stdout")
 		err := fs.Parse(flag.Args()[1:])
 		if err != nil {
 			return err
 		}
 
+		if *fsStream != "" {
+			hash, err := repository.RevParse(*fsStream)
+			if err != nil {
+				return err
+			}
+
+			rc, err := repository.ObjectReader(hash)
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+
+			_, err = io.Copy(os.Stdout, rc)
+			if err != nil {
+				return fmt.Errorf("failed to copy %s: %w", hash, err)
+			}
+			return nil
+		}
+
+		if *fsExists != "" {
+			hash, err := repository.RevParse(*fsExists)
+			if err != nil || !repository.ObjectExists(hash) {
+				return exitCode(1)
+			}
+			return nil
+		}
+
+		if *fsType != "" || *fsSize != "" {
+			rev := *fsType
+			if rev == "" {
+				rev = *fsSize
+			}
+
+			hash, err := repository.RevParse(rev)
+			if err != nil {
+				return err
+			}
+
+			typ, size, err := repository.ReadObjectHeader(hash)
+			if err != nil {
+				return err
+			}
+
+			if *fsType != "" {
+				fmt.Println(typ)
+			} else {
+				fmt.Println(size)
+			}
+			return nil
+		}
+
 		if *fsPrettyPrint == "" {
 			return fmt.Errorf("missing argument -p")
 		}
@@ -65,21 +235,65 @@ func run(root string, command Command) error {
 
 	if command == HashObject {
 		fs := flag.NewFlagSet("hash-file", flag.ContinueOnError)
-		fsWrite := fs.String("w", "", "write")
-		err := fs.Parse(flag.Args()[1:])
+		fsWrite := fs.Bool("w", false, "write the object into the object database")
+		fsType := fs.String("t", "blob", "object type")
+		fsStdin := fs.Bool("stdin", false, "read the object from standard input")
+		operands, err := parseArgs(fs, flag.Args()[1:])
 		if err != nil {
 			return err
 		}
 
-		if *fsWrite == "" {
-			return fmt.Errorf("missing argument -w")
+		/*
+			Without -w nothing is stored, so hashing works outside a repository.
+		*/
+		if !*fsWrite {
+			var body []byte
+			switch {
+			case *fsStdin:
+				body, err = io.ReadAll(os.Stdin)
+			case len(operands) > 0:
+				body, err = os.ReadFile(operands[0])
+			default:
+				return fmt.Errorf("missing argument <file> or --stdin")
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read the object: %w", err)
+			}
+
+			hash, err := git.HashObject(*fsType, body)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(hash)
+			return nil
 		}
 
-		dir := path.Dir(*fsWrite)
-		filename := path.Base(*fsWrite)
-		fsys := os.DirFS(path.Dir(dir))
+		if *fsStdin {
+			body, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read the object: %w", err)
+			}
+
+			hash, err := repository.WriteObject(*fsType, body)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(hash)
+			return nil
+		}
 
-		hash, err := repository.WriteBlob(fsys, filename)
+		if len(operands) == 0 {
+			return fmt.Errorf("missing argument <file> or --stdin")
+		}
+
+		fsys, filename, err := fileFS(operands[0])
+		if err != nil {
+			return err
+		}
+
+		hash, err := repository.WriteObjectFile(fsys, filename, *fsType)
 		if err != nil {
 			return err
 		}
@@ -91,16 +305,51 @@ func run(root string, command Command) error {
 	if command == LsTree {
 		fs := flag.NewFlagSet("ls-tree", flag.ContinueOnError)
 		fsNameOnly := fs.String("name-only", "", "name only")
+		fsNul := fs.Bool("z", false, "terminate entries with NUL instead of newline")
 		err := fs.Parse(flag.Args()[1:])
 		if err != nil {
 			return err
 		}
 
+		treeish := *fsNameOnly
+		if treeish == "" {
+			treeish = fs.Arg(0)
+		}
+		if treeish == "" {
+			return fmt.Errorf("missing argument <tree-ish>")
+		}
+
+		hash, err := repository.ResolveTree(treeish)
+		if err != nil {
+			return err
+		}
+
+		/*
+			With -z names are printed as they are, so names containing newlines stay intact.
+		*/
+		if *fsNul {
+			return repository.EachTreeEntry(hash, func(entry git.TreeEntry) error {
+				line := entry.String()
+				if *fsNameOnly != "" {
+					line = entry.Name
+				}
+
+				fmt.Print(line + "\x00")
+				return nil
+			})
+		}
+
 		if *fsNameOnly == "" {
-			return fmt.Errorf("missing argument --name-only")
+			out, err := repository.ListTree(hash)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(out)
+			return nil
 		}
 
-		out, err := repository.ReadTree(*fsNameOnly)
+		out, err := repository.ReadTree(hash)
 		if err != nil {
 			return err
 		}
@@ -110,7 +359,15 @@ func run(root string, command Command) error {
 	}
 
 	if command == WriteTree {
-		out, err := repository.WriteTree(".")
+		fs := flag.NewFlagSet("write-tree", flag.ContinueOnError)
+		var fsExcludes stringsFlag
+		fs.Var(&fsExcludes, "exclude", "directory name pattern to skip, may be repeated")
+		err := fs.Parse(flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		out, err := repository.WriteTree(".", fsExcludes...)
 		if err != nil {
 			return err
 		}
@@ -119,5 +376,476 @@ func run(root string, command Command) error {
 		return nil
 	}
 
+	if command == HashTree {
+		dir := flag.Arg(1)
+		if dir == "" {
+			dir = "."
+		}
+
+		hash, err := repository.HashWorkTree(dir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(hash)
+		return nil
+	}
+
+	if command == Size {
+		roots := flag.Args()[1:]
+		if len(roots) == 0 {
+			roots = []string{"HEAD"}
+		}
+
+		count, total, err := repository.ReachableSize(roots)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d objects, %d bytes\n", count, total)
+		return nil
+	}
+
+	if command == Archive {
+		treeish := flag.Arg(1)
+		if treeish == "" {
+			return fmt.Errorf("missing argument <tree-ish>")
+		}
+
+		return repository.Archive(treeish, os.Stdout)
+	}
+
+	if command == CheckIgnore {
+		fs := flag.NewFlagSet("check-ignore", flag.ContinueOnError)
+		fsVerbose := fs.Bool("v", false, "verbose")
+		paths, err := parseArgs(fs, flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		if len(paths) == 0 {
+			return fmt.Errorf("missing argument <path>")
+		}
+
+		matches, err := repository.CheckIgnore(paths)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			if *fsVerbose {
+				fmt.Printf("%s:%d:%s\t%s\n", match.Source, match.Line, match.Pattern, match.Path)
+				continue
+			}
+
+			fmt.Println(match.Path)
+		}
+		return nil
+	}
+
+	if command == Grep {
+		fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+		fsIgnoreCase := fs.Bool("i", false, "ignore case")
+		fsLineNumbers := fs.Bool("n", false, "line numbers")
+		err := fs.Parse(flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		if fs.NArg() != 2 {
+			return fmt.Errorf("usage: grep [-i] [-n] <pattern> <tree-ish>")
+		}
+
+		pattern := fs.Arg(0)
+		if *fsIgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+
+		hits, err := repository.Grep(pattern, fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range hits {
+			if *fsLineNumbers {
+				fmt.Printf("%s:%d:%s\n", hit.Path, hit.Line, hit.Text)
+				continue
+			}
+
+			fmt.Printf("%s:%s\n", hit.Path, hit.Text)
+		}
+		return nil
+	}
+
+	if command == Log {
+		fs := flag.NewFlagSet("log", flag.ContinueOnError)
+		fsOneline := fs.Bool("oneline", false, "oneline")
+		fsCount := fs.Int("n", 0, "limit the number of commits")
+		fsFormat := fs.String("format", "", "format")
+		fsStat := fs.Bool("stat", false, "show a summary of the files each commit changes")
+		err := fs.Parse(flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		start := "HEAD"
+		if fs.NArg() > 0 {
+			start = fs.Arg(0)
+		}
+
+		commits, err := repository.Log(start, *fsCount)
+		if errors.Is(err, git.ErrUnbornBranch) {
+			fmt.Println("no commits yet")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		format := *fsFormat
+		if *fsOneline {
+			format = "%h %s"
+		}
+
+		for i, commit := range commits {
+			if format != "" {
+				fmt.Println(commit.Format(format))
+			} else {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Print(commit)
+			}
+
+			/*
+				Like git, merges get no stat since there is no single parent to compare against.
+			*/
+			if !*fsStat || len(commit.Parents) > 1 {
+				continue
+			}
+
+			stats, err := repository.CommitStat(commit.Hash)
+			if err != nil {
+				return err
+			}
+			if len(stats) == 0 {
+				continue
+			}
+
+			if format == "" {
+				fmt.Println()
+			}
+			fmt.Print(git.FormatStat(stats))
+		}
+		return nil
+	}
+
+	if command == WhatChanged {
+		fs := flag.NewFlagSet("whatchanged", flag.ContinueOnError)
+		fsCount := fs.Int("n", 0, "limit the number of commits")
+		err := fs.Parse(flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		start := "HEAD"
+		if fs.NArg() > 0 {
+			start = fs.Arg(0)
+		}
+
+		commits, err := repository.Log(start, *fsCount)
+		if errors.Is(err, git.ErrUnbornBranch) {
+			fmt.Println("no commits yet")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		printed := false
+		for _, commit := range commits {
+			changes, err := repository.CommitChanges(commit.Hash)
+			if err != nil {
+				return err
+			}
+
+			/*
+				Like git, commits that change nothing relative to their first parent are left out.
+			*/
+			if len(changes) == 0 {
+				continue
+			}
+
+			if printed {
+				fmt.Println()
+			}
+			printed = true
+
+			fmt.Print(commit)
+			fmt.Println()
+			for _, change := range changes {
+				fmt.Printf("%s\t%s\n", change.Type, change.Path)
+			}
+		}
+		return nil
+	}
+
+	if command == ListObjects {
+		hashes, err := repository.ListObjects()
+		if err != nil {
+			return err
+		}
+
+		for _, hash := range hashes {
+			fmt.Println(hash)
+		}
+		return nil
+	}
+
+	if command == CommitTree {
+		fs := flag.NewFlagSet("commit-tree", flag.ContinueOnError)
+		var fsParents, fsMessages stringsFlag
+		fs.Var(&fsParents, "p", "parent commit, may be repeated")
+		fs.Var(&fsMessages, "m", "message paragraph, may be repeated")
+
+		/*
+			Git takes the tree before the flags, so keep parsing past positional arguments.
+		*/
+		var positional []string
+		args := flag.Args()[1:]
+		for {
+			err := fs.Parse(args)
+			if err != nil {
+				return err
+			}
+			if fs.NArg() == 0 {
+				break
+			}
+			positional = append(positional, fs.Arg(0))
+			args = fs.Args()[1:]
+		}
+
+		if len(positional) != 1 {
+			return fmt.Errorf("expected a single tree, got: %v", positional)
+		}
+
+		if len(fsMessages) == 0 {
+			return fmt.Errorf("missing argument -m")
+		}
+
+		tree, err := repository.RevParse(positional[0])
+		if err != nil {
+			return err
+		}
+
+		var parents []string
+		for _, parent := range fsParents {
+			hash, err := repository.RevParse(parent)
+			if err != nil {
+				return err
+			}
+			parents = append(parents, hash)
+		}
+
+		hash, err := repository.CommitTree(tree, parents, strings.Join(fsMessages, "\n\n"))
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(hash)
+		return nil
+	}
+
+	if command == Head {
+		head, err := repository.Head()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case head.Detached():
+			fmt.Printf("detached at %s\n", head.Hash)
+		case head.Unborn():
+			fmt.Printf("on %s, no commits yet\n", head.Ref)
+		default:
+			fmt.Printf("on %s at %s\n", head.Ref, head.Hash)
+		}
+		return nil
+	}
+
+	if command == Stash {
+		subcommand := "push"
+		args := flag.Args()[1:]
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			subcommand, args = args[0], args[1:]
+		}
+
+		switch subcommand {
+		case "push", "save":
+			fs := flag.NewFlagSet("stash", flag.ContinueOnError)
+			fsMessage := fs.String("m", "", "message")
+			err := fs.Parse(args)
+			if err != nil {
+				return err
+			}
+
+			_, err = repository.StashSave(*fsMessage)
+			if errors.Is(err, git.ErrNoLocalChanges) {
+				fmt.Println("No local changes to save")
+				return nil
+			}
+			return err
+
+		case "pop":
+			return repository.StashPop()
+
+		default:
+			return fmt.Errorf("unknown stash subcommand %s", subcommand)
+		}
+	}
+
+	if command == Graph {
+		return repository.Graphviz(os.Stdout)
+	}
+
+	if command == ObjectPath {
+		hash := flag.Arg(1)
+		if hash == "" {
+			return fmt.Errorf("missing argument <hash>")
+		}
+
+		objectPath, err := repository.ObjectPath(hash)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(objectPath)
+		return nil
+	}
+
+	if command == PackRefs {
+		fs := flag.NewFlagSet("pack-refs", flag.ContinueOnError)
+		fsAll := fs.Bool("all", false, "pack branch heads too, not just tags")
+		err := fs.Parse(flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		return repository.PackRefs(*fsAll)
+	}
+
+	if command == Config {
+		args := flag.Args()[1:]
+		switch len(args) {
+		case 1:
+			value, found, err := repository.ConfigValue(args[0])
+			if err != nil {
+				return err
+			}
+			if !found {
+				return exitCode(1)
+			}
+
+			fmt.Println(value)
+			return nil
+
+		case 2:
+			return repository.SetConfigValue(args[0], args[1])
+
+		default:
+			return fmt.Errorf("usage: config <key> [<value>]")
+		}
+	}
+
+	if command == Branch {
+		fs := flag.NewFlagSet("branch", flag.ContinueOnError)
+		fsContains := fs.String("contains", "", "only list branches that contain the commit")
+		fsMove := fs.Bool("m", false, "rename a branch")
+		fsForceMove := fs.Bool("M", false, "rename a branch, replacing an existing one")
+		fsDelete := fs.Bool("d", false, "delete a branch merged into HEAD")
+		fsForceDelete := fs.Bool("D", false, "delete a branch even if it is not merged")
+		err := fs.Parse(flag.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		if *fsDelete || *fsForceDelete {
+			if fs.NArg() == 0 {
+				return fmt.Errorf("missing argument <branch>")
+			}
+
+			for _, name := range fs.Args() {
+				err := git.ValidateBranchName(name)
+				if err != nil {
+					return err
+				}
+
+				hash, err := repository.RevParse("refs/heads/" + name)
+				if err != nil {
+					return fmt.Errorf("branch %s not found: %w", name, err)
+				}
+
+				err = repository.DeleteBranch(name, *fsForceDelete)
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Deleted branch %s (was %s).\n", name, hash[:7])
+			}
+			return nil
+		}
+
+		if *fsMove || *fsForceMove {
+			var oldName, newName string
+			switch fs.NArg() {
+			case 1:
+				head, err := repository.Head()
+				if err != nil {
+					return err
+				}
+				if !head.Symbolic {
+					return fmt.Errorf("HEAD is detached, name the branch to rename")
+				}
+				oldName, newName = strings.TrimPrefix(head.Ref, "refs/heads/"), fs.Arg(0)
+			case 2:
+				oldName, newName = fs.Arg(0), fs.Arg(1)
+			default:
+				return fmt.Errorf("usage: branch (-m | -M) [<old>] <new>")
+			}
+
+			return repository.RenameBranch(oldName, newName, *fsForceMove)
+		}
+
+		var names []string
+		if *fsContains != "" {
+			names, err = repository.BranchesContaining(*fsContains)
+			if err != nil {
+				return err
+			}
+		} else {
+			branches, err := repository.Branches()
+			if err != nil {
+				return err
+			}
+			for _, branch := range branches {
+				names = append(names, branch.Name)
+			}
+		}
+
+		head, err := repository.Head()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			marker := "  "
+			if head.Symbolic && head.Ref == "refs/heads/"+name {
+				marker = "* "
+			}
+			fmt.Println(marker + name)
+		}
+		return nil
+	}
+
 	return fmt.Errorf("not implemented %s", command)
 }
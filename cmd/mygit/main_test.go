@@ -0,0 +1,502 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/git-starter-go/cmd/mygit/git"
+)
+
+func TestFileFS(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.MkdirAll(filepath.Join(root, "path", "to", "deep"), 0755)
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(root, "path", "to", "deep", "file.txt"), []byte("deep"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	names := []string{
+		"path/to/deep/file.txt",
+		"./path/to/deep/file.txt",
+		"path/to/deep/file.txt/",
+		filepath.Join(root, "path", "to", "deep", "file.txt"),
+	}
+	for _, name := range names {
+		t.Run("Opens "+name, func(t *testing.T) {
+			fsys, filename, err := fileFS(name)
+			if err != nil {
+				t.Fatalf("error resolving %s: %v", name, err)
+			}
+
+			contents, err := fs.ReadFile(fsys, filename)
+			if err != nil {
+				t.Fatalf("error reading %s: %v", filename, err)
+			}
+
+			if string(contents) != "deep" {
+				t.Fatalf("expected deep, got %q", contents)
+			}
+		})
+	}
+
+	t.Run("Opens a file outside the working directory", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "outside.txt")
+		err := os.WriteFile(outside, []byte("outside"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+
+		fsys, filename, err := fileFS(outside)
+		if err != nil {
+			t.Fatalf("error resolving %s: %v", outside, err)
+		}
+
+		contents, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			t.Fatalf("error reading %s: %v", filename, err)
+		}
+
+		if string(contents) != "outside" {
+			t.Fatalf("expected outside, got %q", contents)
+		}
+	})
+}
+
+// runCommand runs the CLI with args, feeding it stdin and returning what it
+// printed.
+func runCommand(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+
+	err := flag.CommandLine.Parse(args)
+	if err != nil {
+		t.Fatalf("error parsing %v: %v", args, err)
+	}
+
+	inFile := filepath.Join(t.TempDir(), "stdin")
+	err = os.WriteFile(inFile, []byte(stdin), 0644)
+	if err != nil {
+		t.Fatalf("error writing stdin: %v", err)
+	}
+
+	in, err := os.Open(inFile)
+	if err != nil {
+		t.Fatalf("error opening stdin: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("error creating stdout: %v", err)
+	}
+	defer out.Close()
+
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = in, out
+	err = run(".", Command(flag.Arg(0)))
+	os.Stdin, os.Stdout = oldStdin, oldStdout
+	if err != nil {
+		t.Fatalf("error running %v: %v", args, err)
+	}
+
+	contents, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("error reading stdout: %v", err)
+	}
+
+	return string(contents)
+}
+
+func TestHashObjectOutsideRepository(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	const expected = "257cc5642cb1a054f08cc83f2d943e56fd3ebe99\n"
+
+	t.Run("Hashes stdin", func(t *testing.T) {
+		out := runCommand(t, "foo\n", "hash-object", "--stdin")
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	})
+
+	t.Run("Hashes a file", func(t *testing.T) {
+		err := os.WriteFile("foo.txt", []byte("foo\n"), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+
+		out := runCommand(t, "", "hash-object", "foo.txt")
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	})
+
+	_, err = os.Stat(filepath.Join(root, ".git"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no .git directory to be created, got %v", err)
+	}
+}
+
+func TestHashObjectWriteStdin(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	runCommand(t, "", "init")
+
+	t.Run("Writes the object read from stdin", func(t *testing.T) {
+		out := runCommand(t, "foo\n", "hash-object", "-w", "--stdin")
+		expected := "257cc5642cb1a054f08cc83f2d943e56fd3ebe99\n"
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+
+		out = runCommand(t, "", "cat-file", "-p", strings.TrimSpace(expected))
+		if out != "foo\n" {
+			t.Fatalf("expected %q, got %q", "foo\n", out)
+		}
+	})
+}
+
+func TestWhatChanged(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	runGit := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_AUTHOR_DATE=1700000000 +0000",
+			"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_COMMITTER_DATE=1700000000 +0000",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("error running git %v: %v: %s", args, err, out)
+		}
+		return string(out)
+	}
+
+	writeFile := func(name, contents string) {
+		t.Helper()
+		err := os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		err = os.WriteFile(name, []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	runGit("init", "-q", "-b", "main")
+	writeFile("a.txt", "a\n")
+	writeFile("b.txt", "b\n")
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "first")
+
+	writeFile("a.txt", "a2\n")
+	writeFile("dir/c.txt", "c\n")
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "second")
+
+	runGit("rm", "-q", "b.txt")
+	runGit("commit", "-q", "-m", "third")
+
+	t.Run("Lists the files each commit changed", func(t *testing.T) {
+		out := runCommand(t, "", "whatchanged")
+		expected := runGit("log", "--name-status")
+		if out != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, out)
+		}
+
+		for _, line := range []string{"D\tb.txt\n", "M\ta.txt\n", "A\tdir/c.txt\n", "A\tb.txt\n"} {
+			if !strings.Contains(out, line) {
+				t.Fatalf("expected %q in:\n%s", line, out)
+			}
+		}
+	})
+
+	t.Run("Limits the number of commits", func(t *testing.T) {
+		out := runCommand(t, "", "whatchanged", "-n", "1")
+		expected := runGit("log", "-n", "1", "--name-status")
+		if out != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, out)
+		}
+	})
+}
+
+func TestLsTreeNul(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	runGit := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("error running git %v: %v: %s", args, err, out)
+		}
+		return string(out)
+	}
+
+	runGit("init", "-q")
+	for _, name := range []string{"line\nbreak.txt", "with space.txt", "plain.txt"} {
+		err := os.WriteFile(name, []byte(name), 0644)
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+	runGit("add", ".")
+	tree := strings.TrimSpace(runGit("write-tree"))
+
+	t.Run("Separates entries with NUL", func(t *testing.T) {
+		out := runCommand(t, "", "ls-tree", "-z", tree)
+		expected := runGit("ls-tree", "-z", tree)
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+
+		entries := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+		if len(entries) != 3 || !strings.HasSuffix(entries[0], "\tline\nbreak.txt") {
+			t.Fatalf("expected three entries starting with the name containing a newline, got %q", entries)
+		}
+	})
+
+	t.Run("Separates names with NUL", func(t *testing.T) {
+		out := runCommand(t, "", "ls-tree", "-z", "--name-only", tree)
+		expected := "line\nbreak.txt\x00plain.txt\x00with space.txt\x00"
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	})
+
+	t.Run("Lists the tree of a ref", func(t *testing.T) {
+		runGit("-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+
+		out := runCommand(t, "", "ls-tree", "HEAD")
+		expected := runCommand(t, "", "ls-tree", tree)
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+
+		branch := strings.TrimSpace(runGit("symbolic-ref", "--short", "HEAD"))
+		out = runCommand(t, "", "ls-tree", "--name-only", branch)
+		expected = "line\nbreak.txt\nplain.txt\nwith space.txt\n"
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	})
+}
+
+func TestEndOfOptions(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	runGit := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("error running git %v: %v: %s", args, err, out)
+		}
+		return string(out)
+	}
+
+	runGit("init", "-q")
+	err = os.WriteFile("-weird-name.txt", []byte("dash\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	expected := runGit("hash-object", "--", "-weird-name.txt")
+
+	for _, separator := range []string{"--", "--end-of-options"} {
+		t.Run("Hashes a file after "+separator, func(t *testing.T) {
+			out := runCommand(t, "", "hash-object", separator, "-weird-name.txt")
+			if out != expected {
+				t.Fatalf("expected %q, got %q", expected, out)
+			}
+		})
+	}
+
+	t.Run("Writes a file after --", func(t *testing.T) {
+		out := runCommand(t, "", "hash-object", "-w", "--", "-weird-name.txt")
+		if out != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+
+		contents := runGit("cat-file", "-p", strings.TrimSpace(out))
+		if contents != "dash\n" {
+			t.Fatalf("expected the object to be written, got %q", contents)
+		}
+	})
+}
+
+func TestInitBranch(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	runCommand(t, "", "init", "-b", "trunk")
+
+	out, err := exec.Command("git", "symbolic-ref", "HEAD").CombinedOutput()
+	if err != nil {
+		t.Fatalf("error running git symbolic-ref: %v: %s", err, out)
+	}
+
+	if head := strings.TrimSpace(string(out)); head != "refs/heads/trunk" {
+		t.Fatalf("expected HEAD on trunk, got %s", head)
+	}
+}
+
+func TestCatFileExists(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	out, err := exec.Command("git", "init", "-q").CombinedOutput()
+	if err != nil {
+		t.Fatalf("error running git init: %v: %s", err, out)
+	}
+
+	err = os.WriteFile("file.txt", []byte("present\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	out, err = exec.Command("git", "hash-object", "-w", "file.txt").CombinedOutput()
+	if err != nil {
+		t.Fatalf("error running git hash-object: %v: %s", err, out)
+	}
+	present := strings.TrimSpace(string(out))
+
+	tests := []struct {
+		name   string
+		hash   string
+		status int
+	}{
+		{name: "Exits with 0 for a present object", hash: present, status: 0},
+		{name: "Exits with 1 for a missing object", hash: "1111111111111111111111111111111111111111", status: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := flag.CommandLine.Parse([]string{"cat-file", "-e", tt.hash})
+			if err != nil {
+				t.Fatalf("error parsing arguments: %v", err)
+			}
+
+			status := exitStatus(run(".", Command(flag.Arg(0))))
+			if status != tt.status {
+				t.Fatalf("expected exit status %d, got %d", tt.status, status)
+			}
+		})
+	}
+}
+
+func TestBranchDeleteInvalidName(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	root := t.TempDir()
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatalf("error changing directory: %v", err)
+	}
+
+	runCommand(t, "", "init")
+
+	t.Run("Reports the name as invalid", func(t *testing.T) {
+		err := flag.CommandLine.Parse([]string{"branch", "-D", "x/../main"})
+		if err != nil {
+			t.Fatalf("error parsing arguments: %v", err)
+		}
+
+		err = run(".", Command(flag.Arg(0)))
+		if !errors.Is(err, git.ErrInvalidBranchName) {
+			t.Fatalf("expected error %v, got %v", git.ErrInvalidBranchName, err)
+		}
+	})
+}